@@ -45,6 +45,10 @@ const (
 	ExitedEvent     DebugEventType = "exited"
 	TerminatedEvent DebugEventType = "terminated"
 	LaunchEvent     DebugEventType = "launch"
+	// InputRequestedEvent 用户程序阻塞在读取标准输入上
+	InputRequestedEvent DebugEventType = "inputRequested"
+	// InputSatisfiedEvent 用户程序读取到了输入，不再等待
+	InputSatisfiedEvent DebugEventType = "inputSatisfied"
 )
 
 // BreakpointReasonType 断点改变类型
@@ -60,10 +64,26 @@ const (
 type StoppedReasonType string
 
 const (
-	BreakpointStopped StoppedReasonType = "breakpoint"
-	StepStopped       StoppedReasonType = "step"
-	ExitedNormally    StoppedReasonType = "exited-normally"
-	Unknown           StoppedReasonType = "unknown"
+	BreakpointStopped     StoppedReasonType = "breakpoint"
+	StepStopped           StoppedReasonType = "step"
+	DataBreakpointStopped StoppedReasonType = "data breakpoint"
+	PauseStopped          StoppedReasonType = "pause"
+	NoHistoryStopped      StoppedReasonType = "no-history"
+	ExceptionStopped      StoppedReasonType = "exception"
+	ExitedNormally        StoppedReasonType = "exited-normally"
+	Exited                StoppedReasonType = "exited"
+	ExitedSignalled       StoppedReasonType = "exited-signalled"
+	Unknown               StoppedReasonType = "unknown"
+)
+
+// ExceptionFilterType 异常断点过滤器，程序收到对应的信号时停止
+type ExceptionFilterType string
+
+const (
+	SegfaultFilter   ExceptionFilterType = "segfault"
+	AbortFilter      ExceptionFilterType = "abort"
+	FPEFilter        ExceptionFilterType = "fpe"
+	AllSignalsFilter ExceptionFilterType = "all"
 )
 
 // StepType 单步调试类型
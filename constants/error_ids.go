@@ -28,6 +28,9 @@ const (
 	UnableToDisassemble        = 2013
 	UnableToListRegisters      = 2014
 	UnableToRunDlvCommand      = 2015
+	UnableToReadMemory         = 2016
+	UnableToWriteMemory        = 2017
+	UnableToSendToConsole      = 2018
 
 	// Add more codes as we support more requests
 
@@ -48,28 +48,80 @@ func (c *CDebugger) Run() error {
 	return c.gdbDebugger.Run()
 }
 
-func (c *CDebugger) StepOver() error {
-	return c.gdbDebugger.StepOver()
+func (c *CDebugger) StepOver(threadId int, granularity dap.SteppingGranularity) error {
+	return c.gdbDebugger.StepOver(threadId, granularity)
 }
 
-func (c *CDebugger) StepIn() error {
-	return c.gdbDebugger.StepIn()
+func (c *CDebugger) StepIn(threadId int, granularity dap.SteppingGranularity) error {
+	return c.gdbDebugger.StepIn(threadId, granularity)
 }
 
-func (c *CDebugger) StepOut() error {
-	return c.gdbDebugger.StepOut()
+func (c *CDebugger) StepOut(threadId int) error {
+	return c.gdbDebugger.StepOut(threadId)
 }
 
-func (c *CDebugger) Continue() error {
-	return c.gdbDebugger.Continue()
+func (c *CDebugger) Continue(threadId int) error {
+	return c.gdbDebugger.Continue(threadId)
 }
 
-func (c *CDebugger) SetBreakpoints(source dap.Source, breakpoints []dap.SourceBreakpoint) error {
+func (c *CDebugger) SendToConsole(content string, eof bool) error {
+	return c.gdbDebugger.SendToConsole(content, eof)
+}
+
+func (c *CDebugger) Pause() error {
+	return c.gdbDebugger.Pause()
+}
+
+func (c *CDebugger) StepBack(threadId int) error {
+	return c.gdbDebugger.StepBack(threadId)
+}
+
+func (c *CDebugger) ReverseContinue(threadId int) error {
+	return c.gdbDebugger.ReverseContinue(threadId)
+}
+
+func (c *CDebugger) SetBreakpoints(source dap.Source, breakpoints []dap.SourceBreakpoint) ([]dap.Breakpoint, error) {
 	return c.gdbDebugger.SetBreakpoints(source, breakpoints)
 }
 
-func (c *CDebugger) GetStackTrace() ([]dap.StackFrame, error) {
-	return c.gdbDebugger.GetStackTrace()
+func (c *CDebugger) SetFunctionBreakpoints(breakpoints []dap.FunctionBreakpoint) ([]dap.Breakpoint, error) {
+	return c.gdbDebugger.SetFunctionBreakpoints(breakpoints)
+}
+
+func (c *CDebugger) DataBreakpointInfo(reference int, name string, frameId int) (*dap.DataBreakpointInfoResponseBody, error) {
+	return c.gdbDebugger.DataBreakpointInfo(reference, name, frameId)
+}
+
+func (c *CDebugger) SetDataBreakpoints(breakpoints []dap.DataBreakpoint) ([]dap.Breakpoint, error) {
+	return c.gdbDebugger.SetDataBreakpoints(breakpoints)
+}
+
+func (c *CDebugger) SetExceptionBreakpoints(filters []string) error {
+	return c.gdbDebugger.SetExceptionBreakpoints(filters)
+}
+
+func (c *CDebugger) ExceptionInfo(threadId int) (*dap.ExceptionInfoResponseBody, error) {
+	return c.gdbDebugger.ExceptionInfo(threadId)
+}
+
+func (c *CDebugger) GetThreads() ([]dap.Thread, error) {
+	return c.gdbDebugger.GetThreads()
+}
+
+func (c *CDebugger) GetStackTrace(threadId int) ([]dap.StackFrame, error) {
+	return c.gdbDebugger.GetStackTrace(threadId)
+}
+
+func (c *CDebugger) Disassemble(memoryReference string, offset int, instructionOffset int, instructionCount int) ([]dap.DisassembledInstruction, error) {
+	return c.gdbDebugger.Disassemble(memoryReference, offset, instructionOffset, instructionCount)
+}
+
+func (c *CDebugger) ReadMemory(memoryReference string, offset int, count int) (*dap.ReadMemoryResponseBody, error) {
+	return c.gdbDebugger.ReadMemory(memoryReference, offset, count)
+}
+
+func (c *CDebugger) WriteMemory(memoryReference string, offset int, data []byte) (int, error) {
+	return c.gdbDebugger.WriteMemory(memoryReference, offset, data)
 }
 
 func (c *CDebugger) GetScopes(frameId int) ([]dap.Scope, error) {
@@ -97,39 +149,101 @@ func (c *CDebugger) GetVariables(reference int) ([]dap.Variable, error) {
 	return variables, err
 }
 
+func (c *CDebugger) Evaluate(expression string, frameId int, context string) (*dap.Variable, error) {
+	variable, err := c.gdbDebugger.Evaluate(expression, frameId, context)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.processVariable(*variable, NewStructReferenceStruct(strconv.Itoa(frameId), variable.Name, variable.Type))
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *CDebugger) SetVariable(reference int, name string, value string) (*dap.Variable, error) {
+	frameId, expression, err := c.gdbDebugger.ResolveVariableExpression(reference, name)
+	if err != nil {
+		return nil, err
+	}
+	// 全局变量和指针引用没有栈帧，使用当前栈帧即可
+	id, _ := strconv.Atoi(frameId)
+	variable, err := c.SetExpression(expression, value, id)
+	if err != nil {
+		return nil, err
+	}
+	variable.Name = name
+	return variable, nil
+}
+
+func (c *CDebugger) SetExpression(expression string, value string, frameId int) (*dap.Variable, error) {
+	variable, err := c.gdbDebugger.SetExpression(expression, value, frameId)
+	if err != nil {
+		return nil, err
+	}
+	result, err := c.processVariable(*variable, NewStructReferenceStruct(strconv.Itoa(frameId), variable.Name, variable.Type))
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// processVariable 为结构体和指针类型的变量设置引用，使其可以展开，数组类型的变量value设置为数组的首地址
+// structRef为结构体类型的变量展开时使用的引用，结构体成员(FieldPath不为空)无法直接取地址，不处理数组首地址
+func (c *CDebugger) processVariable(variable dap.Variable, structRef *ReferenceStruct) (dap.Variable, error) {
+	var err error
+	if !c.gdbOutputUtil.CheckIsAddress(variable.Value) && variable.IndexedVariables != 0 {
+		// 结构体类型，设置结构体引用
+		variable.VariablesReference, err = c.referenceUtil.CreateVariableReference(structRef)
+		if err != nil {
+			return variable, err
+		}
+	} else if c.gdbOutputUtil.CheckIsAddress(variable.Value) && variable.IndexedVariables != 0 && variable.Type != "char *" {
+		// 指针类型，设置指针引用
+		address := c.gdbOutputUtil.ConvertValueToAddress(variable.Value)
+		variable.Value = address
+		if !c.gdbOutputUtil.IsNullPoint(address) {
+			variable.MemoryReference = address
+			variable.VariablesReference, err = c.referenceUtil.CreateVariableReference(NewPointReferenceStruct(variable.Type, address))
+			if err != nil {
+				return variable, err
+			}
+		}
+	}
+	if structRef.FieldPath != "" {
+		return variable, nil
+	}
+	// 如果是数组类型，设置value为数组的首地址
+	addr, err := c.checkAndSetArrayAddress(variable)
+	if err != nil {
+		log.Printf("checkAndSetArrayAddress failed: %v\n", err)
+	} else if addr != "" {
+		variable.Value = addr
+		variable.MemoryReference = c.gdbOutputUtil.ConvertValueToAddress(addr)
+	}
+	return variable, nil
+}
+
+// isFilteredVariable 判断变量是否是指向程序启动代码的指针，这类变量不返回给用户
+func (c *CDebugger) isFilteredVariable(variable dap.Variable) bool {
+	return c.gdbOutputUtil.CheckIsAddress(variable.Value) && variable.IndexedVariables != 0 &&
+		variable.Type != "char *" && c.gdbOutputUtil.IsShouldBeFilterAddress(variable.Value)
+}
+
 func (c *CDebugger) getLocalScopeVariables(reference int) ([]dap.Variable, error) {
 	variables, err := c.gdbDebugger.GetLocalScopeVariables(reference)
 	if err != nil {
 		return nil, err
 	}
-	frameId := c.referenceUtil.GetFrameIDByLocalReference(reference)
+	frameId := strconv.Itoa(c.referenceUtil.GetFrameIDByLocalReference(reference))
 	var answer []dap.Variable
 	for _, variable := range variables {
-		// 结构体类型，设置结构体引用
-		if !c.gdbOutputUtil.CheckIsAddress(variable.Value) && variable.IndexedVariables != 0 {
-			variable.VariablesReference, err = c.referenceUtil.CreateVariableReference(NewStructReferenceStruct(strconv.Itoa(frameId), variable.Name, variable.Type))
-			if err != nil {
-				return nil, err
-			}
-		} else if c.gdbOutputUtil.CheckIsAddress(variable.Value) && variable.IndexedVariables != 0 && variable.Type != "char *" {
-			if c.gdbOutputUtil.IsShouldBeFilterAddress(variable.Value) {
-				continue
-			}
-			address := c.gdbOutputUtil.ConvertValueToAddress(variable.Value)
-			variable.Value = address
-			if !c.gdbOutputUtil.IsNullPoint(address) {
-				variable.VariablesReference, err = c.referenceUtil.CreateVariableReference(NewPointReferenceStruct(variable.Type, address))
-				if err != nil {
-					return nil, err
-				}
-			}
+		if c.isFilteredVariable(variable) {
+			continue
 		}
-		// 如果是数组类型，设置value为数组的首地址
-		addr, err := c.checkAndSetArrayAddress(variable)
+		variable, err = c.processVariable(variable, NewStructReferenceStruct(frameId, variable.Name, variable.Type))
 		if err != nil {
-			log.Printf("checkAndSetArrayAddress failed: %v\n", err)
-		} else if addr != "" {
-			variable.Value = addr
+			return nil, err
 		}
 		answer = append(answer, variable)
 	}
@@ -144,33 +258,16 @@ func (c *CDebugger) getGlobalScopeVariables() ([]dap.Variable, error) {
 	var answer []dap.Variable
 	// 遍历所有的answer
 	for _, variable := range variables {
-		// 结构体类型，创建结构体引用
-		if !c.gdbOutputUtil.CheckIsAddress(variable.Value) && variable.IndexedVariables != 0 {
-			variable.VariablesReference, err = c.referenceUtil.CreateVariableReference(NewStructReferenceStruct("0", variable.Name, variable.Type))
-			if err != nil {
-				return nil, err
-			}
-			variable.Value = ""
-		} else if c.gdbOutputUtil.CheckIsAddress(variable.Value) && variable.IndexedVariables != 0 && variable.Type != "char *" {
-			if c.gdbOutputUtil.IsShouldBeFilterAddress(variable.Value) {
-				continue
-			}
-			address := c.gdbOutputUtil.ConvertValueToAddress(variable.Value)
-			variable.Value = address
-			if !c.gdbOutputUtil.IsNullPoint(address) {
-				variable.VariablesReference, err = c.referenceUtil.CreateVariableReference(NewPointReferenceStruct(variable.Type, address))
-				if err != nil {
-					return nil, err
-				}
-			}
+		if c.isFilteredVariable(variable) {
+			continue
 		}
-
-		// 如果是数组类型，设置value为数组的首地址
-		addr, err := c.checkAndSetArrayAddress(variable)
+		variable, err = c.processVariable(variable, NewStructReferenceStruct("0", variable.Name, variable.Type))
 		if err != nil {
-			log.Printf("checkAndSetArrayAddress failed: %v\n", err)
-		} else if addr != "" {
-			variable.Value = addr
+			return nil, err
+		}
+		// 全局的结构体变量不显示value，指针和数组的value为地址，会设置MemoryReference
+		if variable.VariablesReference != 0 && variable.MemoryReference == "" {
+			variable.Value = ""
 		}
 		answer = append(answer, variable)
 	}
@@ -214,28 +311,12 @@ func (c *CDebugger) getVariables(reference int) ([]dap.Variable, error) {
 	// 解析c语言结构体，并二次处理
 	answer := make([]dap.Variable, 0, 10)
 	for _, variable := range variables {
-		// 如果value不为指针，且chidren不为0说明是结构体类型
-		if !c.gdbOutputUtil.CheckIsAddress(variable.Value) && variable.IndexedVariables != 0 {
-			variable.VariablesReference, err = c.referenceUtil.CreateVariableReference(GetFieldReferenceStruct(refStruct, variable.Name))
-			if err != nil {
-				return nil, err
-			}
+		if c.isFilteredVariable(variable) {
+			continue
 		}
-		// value指针且chidren不为0说明是指针类型
-		if c.gdbDebugger.GdbOutputUtil.CheckIsAddress(variable.Value) && variable.IndexedVariables != 0 {
-			if variable.Type != "char *" {
-				if c.gdbOutputUtil.IsShouldBeFilterAddress(variable.Value) {
-					continue
-				}
-				address := c.gdbOutputUtil.ConvertValueToAddress(variable.Value)
-				variable.Value = address
-				if !c.gdbOutputUtil.IsNullPoint(address) {
-					variable.VariablesReference, err = c.referenceUtil.CreateVariableReference(NewPointReferenceStruct(variable.Type, address))
-					if err != nil {
-						return nil, err
-					}
-				}
-			}
+		variable, err = c.processVariable(variable, GetFieldReferenceStruct(refStruct, variable.Name))
+		if err != nil {
+			return nil, err
 		}
 		answer = append(answer, variable)
 	}
@@ -243,24 +324,25 @@ func (c *CDebugger) getVariables(reference int) ([]dap.Variable, error) {
 }
 
 // CompileCFile 开始编译文件
-func CompileCFile(workPath string, code string) (string, error) {
+// 返回可执行文件路径以及gcc的输出，编译失败时输出中包含错误信息
+func CompileCFile(workPath string, code string) (string, string, error) {
 	// 创建工作目录, 用户的临时文件
 	if err := os.MkdirAll(workPath, os.ModePerm); err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	// 保存待编译文件
 	codeFile := path.Join(workPath, "main.c")
 	err := os.WriteFile(codeFile, []byte(code), 777)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	execFile := path.Join(workPath, "main")
 
-	cmd := exec.Command("gcc", "-g", "-o", execFile, codeFile)
-	_, err = cmd.Output()
+	cmd := exec.Command("gcc", "-g", "-fdiagnostics-color=never", "-o", execFile, codeFile)
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return "", err
+		return "", string(output), err
 	}
-	return execFile, err
+	return execFile, string(output), err
 }
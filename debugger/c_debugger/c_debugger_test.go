@@ -3,13 +3,13 @@ package c_debugger
 import (
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"path"
 	"testing"
 
 	"github.com/google/go-dap"
 
+	"github.com/fansqz/go-debugger/constants"
 	"github.com/fansqz/go-debugger/debugger"
 	"github.com/fansqz/go-debugger/utils"
 	"github.com/stretchr/testify/assert"
@@ -60,12 +60,27 @@ func (h *testHelper) cleanup() {
 }
 
 // waitForEvent 等待并验证事件
+// 用户程序的输出随时可能到达，跳过stdout类型的输出事件
 func (h *testHelper) waitForEvent(expectedEvent string) dap.EventMessage {
 	event := <-h.eventCh
+	for isStdoutEvent(event) || isInputStateEvent(event) {
+		event = <-h.eventCh
+	}
 	assert.Equal(h.t, expectedEvent, event.GetEvent().Event)
 	return event
 }
 
+func isStdoutEvent(event dap.EventMessage) bool {
+	output, ok := event.(*dap.OutputEvent)
+	return ok && output.Body.Category == "stdout"
+}
+
+// isInputStateEvent 等待输入的事件依赖定时检查，出现的时机不确定
+func isInputStateEvent(event dap.EventMessage) bool {
+	name := event.GetEvent().Event
+	return name == string(constants.InputRequestedEvent) || name == string(constants.InputSatisfiedEvent)
+}
+
 // TestDebug 测试普通调试功能
 func TestDebug(t *testing.T) {
 	helper := newTestHelper(t)
@@ -74,7 +89,7 @@ func TestDebug(t *testing.T) {
 	helper.setup("debug.c")
 
 	// 设置断点
-	err := helper.debug.SetBreakpoints(dap.Source{Path: "main.c"}, []dap.SourceBreakpoint{
+	_, err := helper.debug.SetBreakpoints(dap.Source{Path: "main.c"}, []dap.SourceBreakpoint{
 		{Line: 3},
 		{Line: 7},
 	})
@@ -88,26 +103,28 @@ func TestDebug(t *testing.T) {
 	assert.Equal(t, 3, getStoppedLine(helper.debug))
 
 	// 测试单步执行
-	err = helper.debug.StepOver()
+	err = helper.debug.StepOver(0, "")
 	assert.Nil(t, err)
 	helper.waitForEvent("continued")
 	helper.waitForEvent("stopped")
 	assert.Equal(t, 5, getStoppedLine(helper.debug))
 
 	// 测试继续执行
-	err = helper.debug.Continue()
+	err = helper.debug.Continue(0)
 	assert.Nil(t, err)
 	helper.waitForEvent("continued")
 
 	// 模拟用户输入
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-	io.WriteString(w, "10\n")
+	err = helper.debug.SendToConsole("10\n", false)
+	assert.Nil(t, err)
 	helper.waitForEvent("stopped")
 
 	// 验证程序结束
-	err = helper.debug.Continue()
+	err = helper.debug.Continue(0)
 	helper.waitForEvent("continued")
+	helper.waitForEvent("output")
+	exited := helper.waitForEvent("exited").(*debugger.ExitedEventMessage)
+	assert.Equal(t, 0, exited.Body.ExitCode)
 	helper.waitForEvent("terminated")
 }
 
@@ -119,7 +136,7 @@ func TestVariable(t *testing.T) {
 	helper.setup("variable.c")
 
 	// 设置断点
-	err := helper.debug.SetBreakpoints(dap.Source{Path: "main.c"}, []dap.SourceBreakpoint{
+	_, err := helper.debug.SetBreakpoints(dap.Source{Path: "main.c"}, []dap.SourceBreakpoint{
 		{Line: 55},
 		{Line: 76},
 	})
@@ -132,7 +149,7 @@ func TestVariable(t *testing.T) {
 	helper.waitForEvent("stopped")
 
 	// 获取并验证栈帧信息
-	stacks, err := helper.debug.GetStackTrace()
+	stacks, err := helper.debug.GetStackTrace(0)
 	assert.Nil(t, err)
 
 	// 验证作用域
@@ -149,7 +166,7 @@ func TestVariable(t *testing.T) {
 	verifyLocalVariables(t, helper.debug, scopes[1].VariablesReference)
 
 	// 继续执行到下一个断点
-	err = helper.debug.Continue()
+	err = helper.debug.Continue(0)
 	assert.Nil(t, err)
 	helper.waitForEvent("continued")
 	helper.waitForEvent("stopped")
@@ -211,7 +228,7 @@ func TestLink(t *testing.T) {
 	helper.setup("link.c")
 
 	// 设置断点
-	err := helper.debug.SetBreakpoints(dap.Source{Path: "main.c"}, []dap.SourceBreakpoint{
+	_, err := helper.debug.SetBreakpoints(dap.Source{Path: "main.c"}, []dap.SourceBreakpoint{
 		{Line: 28},
 	})
 	assert.Nil(t, err)
@@ -223,7 +240,7 @@ func TestLink(t *testing.T) {
 	helper.waitForEvent("stopped")
 
 	// 验证链表结构
-	stacks, err := helper.debug.GetStackTrace()
+	stacks, err := helper.debug.GetStackTrace(0)
 	assert.Nil(t, err)
 	scopes, err := helper.debug.GetScopes(stacks[0].Id)
 	assert.Nil(t, err)
@@ -247,7 +264,7 @@ func TestStruct(t *testing.T) {
 	helper.setup("struct.c")
 
 	// 设置断点
-	err := helper.debug.SetBreakpoints(dap.Source{Path: "main.c"}, []dap.SourceBreakpoint{
+	_, err := helper.debug.SetBreakpoints(dap.Source{Path: "main.c"}, []dap.SourceBreakpoint{
 		{Line: 39},
 	})
 	assert.Nil(t, err)
@@ -259,7 +276,7 @@ func TestStruct(t *testing.T) {
 	helper.waitForEvent("stopped")
 
 	// 验证结构体
-	stacks, err := helper.debug.GetStackTrace()
+	stacks, err := helper.debug.GetStackTrace(0)
 	assert.Nil(t, err)
 	scopes, err := helper.debug.GetScopes(stacks[0].Id)
 	assert.Nil(t, err)
@@ -301,7 +318,7 @@ func verifyStudentStruct(t *testing.T, debug *CDebugger, variable dap.Variable)
 
 // getStoppedLine 获取当前停止的行号
 func getStoppedLine(gdb debugger.Debugger) int {
-	stackTrace, _ := gdb.GetStackTrace()
+	stackTrace, _ := gdb.GetStackTrace(0)
 	if len(stackTrace) != 0 {
 		return stackTrace[0].Line
 	}
@@ -320,7 +337,7 @@ func compileFile(workPath string, cFile string) (string, string, error) {
 	if err != nil {
 		return "", "", err
 	}
-	execFile, err := CompileCFile(workPath, string(code))
+	execFile, _, err := CompileCFile(workPath, string(code))
 
 	return execFile, string(code), err
 }
@@ -331,7 +348,7 @@ func TestMatrix(t *testing.T) {
 	defer helper.cleanup()
 	helper.setup("matrix.c")
 	// 设置断点
-	err := helper.debug.SetBreakpoints(dap.Source{Path: "main.c"}, []dap.SourceBreakpoint{
+	_, err := helper.debug.SetBreakpoints(dap.Source{Path: "main.c"}, []dap.SourceBreakpoint{
 		{Line: 20},
 	})
 	assert.Nil(t, err)
@@ -351,7 +368,7 @@ func TestMatrix(t *testing.T) {
 }
 
 func getTargetLocalsVariable(t *testing.T, debugger debugger.Debugger, name string) (dap.Variable, error) {
-	stacks, err := debugger.GetStackTrace()
+	stacks, err := debugger.GetStackTrace(0)
 	assert.Nil(t, err)
 	scopes, err := debugger.GetScopes(stacks[0].Id)
 	assert.Nil(t, err)
@@ -0,0 +1,59 @@
+package debugger
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DiagnosticSeverity 编译诊断信息的级别
+type DiagnosticSeverity string
+
+const (
+	SeverityError   DiagnosticSeverity = "error"
+	SeverityWarning DiagnosticSeverity = "warning"
+	SeverityNote    DiagnosticSeverity = "note"
+)
+
+// CompileDiagnostic gcc/g++输出的一条诊断信息
+type CompileDiagnostic struct {
+	File     string             `json:"file,omitempty"`
+	Line     int                `json:"line,omitempty"`
+	Column   int                `json:"column,omitempty"`
+	Severity DiagnosticSeverity `json:"severity"`
+	Message  string             `json:"message"`
+}
+
+// diagnosticRegexp 匹配 file:line[:column]: severity: message 格式的诊断信息
+var diagnosticRegexp = regexp.MustCompile(`^(.+?):(\d+):(?:(\d+):)?\s*(fatal error|error|warning|note):\s*(.*)$`)
+
+// ParseCompileDiagnostics 解析gcc/g++的编译输出
+// 例如：/var/fanCode/tempDir/xxx/main.c:5:3: error: expected ';' before 'return'
+// 链接错误没有行号信息，例如：main.c:(.text+0x13): undefined reference to `foo'，只保留错误信息
+func ParseCompileDiagnostics(output string) []CompileDiagnostic {
+	var answer []CompileDiagnostic
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if match := diagnosticRegexp.FindStringSubmatch(line); match != nil {
+			lineNumber, _ := strconv.Atoi(match[2])
+			column, _ := strconv.Atoi(match[3])
+			severity := DiagnosticSeverity(match[4])
+			if match[4] == "fatal error" {
+				severity = SeverityError
+			}
+			answer = append(answer, CompileDiagnostic{
+				File:     match[1],
+				Line:     lineNumber,
+				Column:   column,
+				Severity: severity,
+				Message:  match[5],
+			})
+		} else if strings.Contains(line, "undefined reference to") {
+			answer = append(answer, CompileDiagnostic{
+				Severity: SeverityError,
+				Message:  strings.TrimSpace(line),
+			})
+		}
+	}
+	return answer
+}
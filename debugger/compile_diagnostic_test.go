@@ -0,0 +1,36 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCompileDiagnostics(t *testing.T) {
+	output := `/var/fanCode/tempDir/1/main.c: In function 'main':
+/var/fanCode/tempDir/1/main.c:5:5: warning: unused variable 'a' [-Wunused-variable]
+    5 |     int a;
+      |     ^
+/var/fanCode/tempDir/1/main.c:6:13: error: expected ';' before 'return'
+    6 |     printf("")
+      |             ^
+/var/fanCode/tempDir/1/main.c:1:10: fatal error: foo.h: No such file or directory
+/usr/bin/ld: /tmp/ccx.o: in function ` + "`main'" + `:
+main.c:(.text+0x13): undefined reference to ` + "`foo'" + `
+collect2: error: ld returned 1 exit status
+`
+	diagnostics := ParseCompileDiagnostics(output)
+	assert.Equal(t, []CompileDiagnostic{
+		{File: "/var/fanCode/tempDir/1/main.c", Line: 5, Column: 5, Severity: SeverityWarning, Message: "unused variable 'a' [-Wunused-variable]"},
+		{File: "/var/fanCode/tempDir/1/main.c", Line: 6, Column: 13, Severity: SeverityError, Message: "expected ';' before 'return'"},
+		{File: "/var/fanCode/tempDir/1/main.c", Line: 1, Column: 10, Severity: SeverityError, Message: "foo.h: No such file or directory"},
+		{Severity: SeverityError, Message: "main.c:(.text+0x13): undefined reference to `foo'"},
+	}, diagnostics)
+}
+
+func TestParseCompileDiagnosticsWithoutColumn(t *testing.T) {
+	diagnostics := ParseCompileDiagnostics("main.cpp:12: error: 'x' was not declared in this scope\n")
+	assert.Equal(t, []CompileDiagnostic{
+		{File: "main.cpp", Line: 12, Severity: SeverityError, Message: "'x' was not declared in this scope"},
+	}, diagnostics)
+}
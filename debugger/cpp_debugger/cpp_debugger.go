@@ -49,22 +49,67 @@ func (c *CPPDebugger) Start(option *StartOption) error {
 }
 
 // 代理方法 - 直接调用底层GDB调试器
-func (c *CPPDebugger) Run() error                               { return c.gdbDebugger.Run() }
-func (c *CPPDebugger) StepOver() error                          { return c.gdbDebugger.StepOver() }
-func (c *CPPDebugger) StepIn() error                            { return c.gdbDebugger.StepIn() }
-func (c *CPPDebugger) StepOut() error                           { return c.gdbDebugger.StepOut() }
-func (c *CPPDebugger) Continue() error                          { return c.gdbDebugger.Continue() }
-func (c *CPPDebugger) Terminate() error                         { return c.gdbDebugger.Terminate() }
-func (c *CPPDebugger) GetStackTrace() ([]dap.StackFrame, error) { return c.gdbDebugger.GetStackTrace() }
+func (c *CPPDebugger) Run() error { return c.gdbDebugger.Run() }
+func (c *CPPDebugger) SendToConsole(content string, eof bool) error {
+	return c.gdbDebugger.SendToConsole(content, eof)
+}
+func (c *CPPDebugger) StepOver(threadId int, granularity dap.SteppingGranularity) error {
+	return c.gdbDebugger.StepOver(threadId, granularity)
+}
+func (c *CPPDebugger) StepIn(threadId int, granularity dap.SteppingGranularity) error {
+	return c.gdbDebugger.StepIn(threadId, granularity)
+}
+func (c *CPPDebugger) StepOut(threadId int) error  { return c.gdbDebugger.StepOut(threadId) }
+func (c *CPPDebugger) Continue(threadId int) error { return c.gdbDebugger.Continue(threadId) }
+func (c *CPPDebugger) Pause() error                { return c.gdbDebugger.Pause() }
+func (c *CPPDebugger) StepBack(threadId int) error { return c.gdbDebugger.StepBack(threadId) }
+func (c *CPPDebugger) ReverseContinue(threadId int) error {
+	return c.gdbDebugger.ReverseContinue(threadId)
+}
+func (c *CPPDebugger) Terminate() error                  { return c.gdbDebugger.Terminate() }
+func (c *CPPDebugger) GetThreads() ([]dap.Thread, error) { return c.gdbDebugger.GetThreads() }
+func (c *CPPDebugger) GetStackTrace(threadId int) ([]dap.StackFrame, error) {
+	return c.gdbDebugger.GetStackTrace(threadId)
+}
+func (c *CPPDebugger) Disassemble(memoryReference string, offset int, instructionOffset int, instructionCount int) ([]dap.DisassembledInstruction, error) {
+	return c.gdbDebugger.Disassemble(memoryReference, offset, instructionOffset, instructionCount)
+}
+func (c *CPPDebugger) ReadMemory(memoryReference string, offset int, count int) (*dap.ReadMemoryResponseBody, error) {
+	return c.gdbDebugger.ReadMemory(memoryReference, offset, count)
+}
+func (c *CPPDebugger) WriteMemory(memoryReference string, offset int, data []byte) (int, error) {
+	return c.gdbDebugger.WriteMemory(memoryReference, offset, data)
+}
+func (c *CPPDebugger) SetExceptionBreakpoints(filters []string) error {
+	return c.gdbDebugger.SetExceptionBreakpoints(filters)
+}
+func (c *CPPDebugger) ExceptionInfo(threadId int) (*dap.ExceptionInfoResponseBody, error) {
+	return c.gdbDebugger.ExceptionInfo(threadId)
+}
 func (c *CPPDebugger) GetScopes(frameId int) ([]dap.Scope, error) {
 	return c.gdbDebugger.GetScopes(frameId)
 }
 
 // SetBreakpoints 设置断点
-func (c *CPPDebugger) SetBreakpoints(source dap.Source, breakpoints []dap.SourceBreakpoint) error {
+func (c *CPPDebugger) SetBreakpoints(source dap.Source, breakpoints []dap.SourceBreakpoint) ([]dap.Breakpoint, error) {
 	return c.gdbDebugger.SetBreakpoints(source, breakpoints)
 }
 
+// SetFunctionBreakpoints 设置函数断点
+func (c *CPPDebugger) SetFunctionBreakpoints(breakpoints []dap.FunctionBreakpoint) ([]dap.Breakpoint, error) {
+	return c.gdbDebugger.SetFunctionBreakpoints(breakpoints)
+}
+
+// DataBreakpointInfo 获取变量的数据断点信息
+func (c *CPPDebugger) DataBreakpointInfo(reference int, name string, frameId int) (*dap.DataBreakpointInfoResponseBody, error) {
+	return c.gdbDebugger.DataBreakpointInfo(reference, name, frameId)
+}
+
+// SetDataBreakpoints 设置数据断点
+func (c *CPPDebugger) SetDataBreakpoints(breakpoints []dap.DataBreakpoint) ([]dap.Breakpoint, error) {
+	return c.gdbDebugger.SetDataBreakpoints(breakpoints)
+}
+
 // GetVariables 获取变量列表，根据引用类型分发到不同的处理方法
 // C++调试需要特殊处理，因为可能存在public、private等访问修饰符
 func (c *CPPDebugger) GetVariables(reference int) ([]dap.Variable, error) {
@@ -82,18 +127,61 @@ func (c *CPPDebugger) GetVariables(reference int) ([]dap.Variable, error) {
 	}
 }
 
+// Evaluate 计算表达式，结构体和指针类型的结果会设置引用，可以继续展开
+func (c *CPPDebugger) Evaluate(expression string, frameId int, context string) (*dap.Variable, error) {
+	variable, err := c.gdbDebugger.Evaluate(expression, frameId, context)
+	if err != nil {
+		return nil, err
+	}
+	result := c.processVariable(*variable, strconv.Itoa(frameId))
+	return &result, nil
+}
+
+// SetVariable 修改变量的值，结构体成员的表达式需要使用C++的表达式规则构建
+func (c *CPPDebugger) SetVariable(reference int, name string, value string) (*dap.Variable, error) {
+	frameId, expression, err := c.gdbDebugger.ResolveVariableExpression(reference, name)
+	if err != nil {
+		return nil, err
+	}
+	if !c.referenceUtil.CheckIsScopeReference(reference) {
+		refStruct, err := c.referenceUtil.ParseVariableReference(reference)
+		if err != nil {
+			return nil, err
+		}
+		expression = c.GetExport(GetFieldReferenceStruct(refStruct, name))
+	}
+	// 全局变量和指针引用没有栈帧，使用当前栈帧即可
+	id, _ := strconv.Atoi(frameId)
+	variable, err := c.SetExpression(expression, value, id)
+	if err != nil {
+		return nil, err
+	}
+	variable.Name = name
+	return variable, nil
+}
+
+// SetExpression 修改表达式的值
+func (c *CPPDebugger) SetExpression(expression string, value string, frameId int) (*dap.Variable, error) {
+	variable, err := c.gdbDebugger.SetExpression(expression, value, frameId)
+	if err != nil {
+		return nil, err
+	}
+	result := c.processVariable(*variable, strconv.Itoa(frameId))
+	return &result, nil
+}
+
 // CompileCPPFile 编译C++文件
-// 使用G++编译器，启用调试信息和优化选项
-func CompileCPPFile(workPath string, code string) (string, error) {
+// 使用G++编译器，启用调试信息和优化选项，返回可执行文件路径以及g++的输出
+func CompileCPPFile(workPath string, code string) (string, string, error) {
 	// 创建工作目录
 	if err := os.MkdirAll(workPath, os.ModePerm); err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	// 保存源代码文件
 	codeFile := path.Join(workPath, "main.cpp")
 	if err := os.WriteFile(codeFile, []byte(code), 0777); err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	// 编译选项说明：
@@ -105,16 +193,19 @@ func CompileCPPFile(workPath string, code string) (string, error) {
 	// -fno-omit-frame-pointer: 保留帧指针
 	// -fno-reorder-blocks-and-partition: 禁用块重排序
 	// -fvar-tracking-assignments: 启用变量跟踪
+	// -fdiagnostics-color=never: 输出不带颜色，便于解析诊断信息
 	execFile := path.Join(workPath, "main")
 	cmd := exec.Command("g++", "-g", "-O0",
 		"-fno-inline-functions",
 		"-ftrivial-auto-var-init=zero", "-fsanitize=undefined", "-fno-omit-frame-pointer",
-		"-fno-reorder-blocks-and-partition", "-fvar-tracking-assignments", codeFile, "-o", execFile)
+		"-fno-reorder-blocks-and-partition", "-fvar-tracking-assignments", "-fdiagnostics-color=never",
+		codeFile, "-o", execFile)
 
-	if _, err := cmd.Output(); err != nil {
-		return "", err
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", string(output), err
 	}
-	return execFile, nil
+	return execFile, string(output), nil
 }
 
 // processVariable 处理单个变量的通用逻辑
@@ -135,6 +226,7 @@ func (c *CPPDebugger) processVariable(variable dap.Variable, frameId string) dap
 			variable.Value = address
 			// 非空指针才创建引用
 			if !c.gdbOutputUtil.IsNullPoint(address) {
+				variable.MemoryReference = address
 				variable.VariablesReference, _ = c.referenceUtil.CreateVariableReference(NewPointReferenceStruct(variable.Type, address))
 			}
 		}
@@ -143,6 +235,7 @@ func (c *CPPDebugger) processVariable(variable dap.Variable, frameId string) dap
 	// 处理数组类型：设置value为数组的首地址，便于数组可视化
 	if addr, err := c.checkAndSetArrayAddress(variable); err == nil && addr != "" {
 		variable.Value = addr
+		variable.MemoryReference = c.gdbOutputUtil.ConvertValueToAddress(addr)
 	}
 
 	return variable
@@ -225,6 +318,7 @@ func (c *CPPDebugger) getVariables(reference int) ([]dap.Variable, error) {
 				variable.Value = address
 
 				if !c.gdbOutputUtil.IsNullPoint(address) {
+					variable.MemoryReference = address
 					variable.VariablesReference, _ = c.referenceUtil.CreateVariableReference(NewPointReferenceStruct(variable.Type, address))
 				}
 			}
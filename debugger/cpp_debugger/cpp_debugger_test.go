@@ -2,13 +2,13 @@ package cpp_debugger
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"path"
 	"testing"
 
 	"github.com/google/go-dap"
 
+	"github.com/fansqz/go-debugger/constants"
 	"github.com/fansqz/go-debugger/debugger"
 	"github.com/fansqz/go-debugger/utils"
 	"github.com/stretchr/testify/assert"
@@ -59,12 +59,27 @@ func (h *testHelper) cleanup() {
 }
 
 // waitForEvent 等待并验证事件
+// 用户程序的输出随时可能到达，跳过stdout类型的输出事件
 func (h *testHelper) waitForEvent(expectedEvent string) dap.EventMessage {
 	event := <-h.eventCh
+	for isStdoutEvent(event) || isInputStateEvent(event) {
+		event = <-h.eventCh
+	}
 	assert.Equal(h.t, expectedEvent, event.GetEvent().Event)
 	return event
 }
 
+func isStdoutEvent(event dap.EventMessage) bool {
+	output, ok := event.(*dap.OutputEvent)
+	return ok && output.Body.Category == "stdout"
+}
+
+// isInputStateEvent 等待输入的事件依赖定时检查，出现的时机不确定
+func isInputStateEvent(event dap.EventMessage) bool {
+	name := event.GetEvent().Event
+	return name == string(constants.InputRequestedEvent) || name == string(constants.InputSatisfiedEvent)
+}
+
 // TestDebug 测试普通调试功能
 func TestDebug(t *testing.T) {
 	helper := newTestHelper(t)
@@ -73,7 +88,7 @@ func TestDebug(t *testing.T) {
 	helper.setup("debug.cpp")
 
 	// 设置断点
-	err := helper.debug.SetBreakpoints(dap.Source{Path: "main.cpp"}, []dap.SourceBreakpoint{
+	_, err := helper.debug.SetBreakpoints(dap.Source{Path: "main.cpp"}, []dap.SourceBreakpoint{
 		{Line: 3},
 		{Line: 7},
 	})
@@ -87,26 +102,28 @@ func TestDebug(t *testing.T) {
 	assert.Equal(t, 3, getStoppedLine(helper.debug))
 
 	// 测试单步执行
-	err = helper.debug.StepOver()
+	err = helper.debug.StepOver(0, "")
 	assert.Nil(t, err)
 	helper.waitForEvent("continued")
 	helper.waitForEvent("stopped")
 	assert.Equal(t, 4, getStoppedLine(helper.debug))
 
 	// 测试继续执行
-	err = helper.debug.Continue()
+	err = helper.debug.Continue(0)
 	assert.Nil(t, err)
 	helper.waitForEvent("continued")
 
 	// 模拟用户输入
-	r, w, _ := os.Pipe()
-	os.Stdin = r
-	io.WriteString(w, "10\n")
+	err = helper.debug.SendToConsole("10\n", false)
+	assert.Nil(t, err)
 	helper.waitForEvent("stopped")
 
 	// 验证程序结束
-	err = helper.debug.Continue()
+	err = helper.debug.Continue(0)
 	helper.waitForEvent("continued")
+	helper.waitForEvent("output")
+	exited := helper.waitForEvent("exited").(*debugger.ExitedEventMessage)
+	assert.Equal(t, 0, exited.Body.ExitCode)
 	helper.waitForEvent("terminated")
 }
 
@@ -118,7 +135,7 @@ func TestVariable(t *testing.T) {
 	helper.setup("variable.cpp")
 
 	// 设置断点
-	err := helper.debug.SetBreakpoints(dap.Source{Path: "main.cpp"}, []dap.SourceBreakpoint{
+	_, err := helper.debug.SetBreakpoints(dap.Source{Path: "main.cpp"}, []dap.SourceBreakpoint{
 		{Line: 64},
 		{Line: 82},
 	})
@@ -131,7 +148,7 @@ func TestVariable(t *testing.T) {
 	helper.waitForEvent("stopped")
 
 	// 获取并验证栈帧信息
-	stacks, err := helper.debug.GetStackTrace()
+	stacks, err := helper.debug.GetStackTrace(0)
 	assert.Nil(t, err)
 
 	// 验证作用域
@@ -148,7 +165,7 @@ func TestVariable(t *testing.T) {
 	verifyLocalVariables(t, helper.debug, scopes[1].VariablesReference)
 
 	// 继续执行到下一个断点
-	err = helper.debug.Continue()
+	err = helper.debug.Continue(0)
 	assert.Nil(t, err)
 	helper.waitForEvent("continued")
 	helper.waitForEvent("stopped")
@@ -238,7 +255,7 @@ func verifyLocalVariables2(t *testing.T, debug *CPPDebugger, ref int) {
 
 // getStoppedLine 获取当前停止的行号
 func getStoppedLine(gdb debugger.Debugger) int {
-	stackTrace, _ := gdb.GetStackTrace()
+	stackTrace, _ := gdb.GetStackTrace(0)
 	if len(stackTrace) != 0 {
 		return stackTrace[0].Line
 	}
@@ -258,7 +275,7 @@ func compileFile(workPath string, cppFile string) (string, string, error) {
 		return "", "", err
 	}
 	// 编译文件
-	execFile, err := CompileCPPFile(workPath, string(code))
+	execFile, _, err := CompileCPPFile(workPath, string(code))
 	return execFile, string(code), err
 }
 
@@ -270,7 +287,7 @@ func TestLink(t *testing.T) {
 	helper.setup("link.cpp")
 
 	// 设置断点
-	err := helper.debug.SetBreakpoints(dap.Source{Path: "main.cpp"}, []dap.SourceBreakpoint{
+	_, err := helper.debug.SetBreakpoints(dap.Source{Path: "main.cpp"}, []dap.SourceBreakpoint{
 		{Line: 24},
 	})
 	assert.Nil(t, err)
@@ -282,7 +299,7 @@ func TestLink(t *testing.T) {
 	helper.waitForEvent("stopped")
 
 	// 验证链表结构
-	stacks, err := helper.debug.GetStackTrace()
+	stacks, err := helper.debug.GetStackTrace(0)
 	assert.Nil(t, err)
 	scopes, err := helper.debug.GetScopes(stacks[0].Id)
 	assert.Nil(t, err)
@@ -360,7 +377,7 @@ func TestArrayVariables(t *testing.T) {
 	helper.setup("array.cpp")
 
 	// 设置断点，假设 main 函数数组定义后第一个可断点行是 40
-	err := helper.debug.SetBreakpoints(dap.Source{Path: "main.cpp"}, []dap.SourceBreakpoint{
+	_, err := helper.debug.SetBreakpoints(dap.Source{Path: "main.cpp"}, []dap.SourceBreakpoint{
 		{Line: 49},
 	})
 	assert.Nil(t, err)
@@ -372,7 +389,7 @@ func TestArrayVariables(t *testing.T) {
 	helper.waitForEvent("stopped")
 
 	// 获取栈帧和作用域
-	stacks, err := helper.debug.GetStackTrace()
+	stacks, err := helper.debug.GetStackTrace(0)
 	assert.Nil(t, err)
 	scopes, err := helper.debug.GetScopes(stacks[0].Id)
 	assert.Nil(t, err)
@@ -2,6 +2,7 @@ package debugger
 
 import (
 	"github.com/fansqz/go-debugger/constants"
+	"github.com/google/go-dap"
 )
 
 // StartOption 启动调试的参数
@@ -9,6 +10,19 @@ type StartOption struct {
 	ExecFile string
 	// Main文件代码
 	MainCode string
+	// Args 用户程序的命令行参数
+	Args []string
+	// Env 用户程序的环境变量
+	Env map[string]string
+	// Cwd 用户程序的工作目录
+	Cwd string
+	// ProcessId 不为0时附加到已经运行的进程，而不是启动新进程
+	ProcessId int
+	// SourceMap 服务端源文件路径到客户端路径的映射，编译用户代码时使用，
+	// 客户端用自己的路径设置断点，返回的栈帧等源文件也使用客户端的路径
+	SourceMap map[string]string
+	// Record 为true时在程序第一次停止时开启执行记录，用于反向调试
+	Record bool
 	// Callback 事件回调
 	Callback NotificationCallback
 }
@@ -112,8 +126,8 @@ func NewContinuedEvent() *ContinuedEvent {
 // ExitedEvent
 // 该event表明被调试对象已经退出并返回exit code。但是并不意味着调试会话结束
 type ExitedEvent struct {
-	ExitCode int
-	Message  string
+	ExitCode int    `json:"exitCode"`
+	Message  string `json:"message"` // 退出的描述，被信号终止时包含信号信息
 }
 
 func NewExitedEvent(code int, message string) *ExitedEvent {
@@ -123,11 +137,26 @@ func NewExitedEvent(code int, message string) *ExitedEvent {
 	}
 }
 
+// ExitedEventMessage 以DAP exited事件的形式发送ExitedEvent，body在exitCode之外增加了message
+type ExitedEventMessage struct {
+	dap.Event
+	Body *ExitedEvent `json:"body"`
+}
+
+func NewExitedEventMessage(body *ExitedEvent) *ExitedEventMessage {
+	return &ExitedEventMessage{
+		Event: *NewEvent(0, string(constants.ExitedEvent)),
+		Body:  body,
+	}
+}
+
 // CompileEvent
 // 编译事件
 type CompileEvent struct {
-	Success bool
-	Message string // 编译产生的信息
+	Success bool   `json:"success"`
+	Message string `json:"message"` // 编译产生的信息
+	// Diagnostics 解析后的编译诊断信息，包括错误和警告
+	Diagnostics []CompileDiagnostic `json:"diagnostics"`
 }
 
 func NewCompileEvent(success bool, message string) *CompileEvent {
@@ -137,6 +166,19 @@ func NewCompileEvent(success bool, message string) *CompileEvent {
 	}
 }
 
+// CompileEventMessage 以DAP自定义事件compile的形式发送CompileEvent
+type CompileEventMessage struct {
+	dap.Event
+	Body *CompileEvent `json:"body"`
+}
+
+func NewCompileEventMessage(body *CompileEvent) *CompileEventMessage {
+	return &CompileEventMessage{
+		Event: *NewEvent(0, string(constants.CompileEvent)),
+		Body:  body,
+	}
+}
+
 // LaunchEvent
 // 调试资源准备成功
 type LaunchEvent struct {
@@ -16,22 +16,56 @@ type Debugger interface {
 	Start(option *StartOption) error
 	// Run 启动程序执行
 	Run() error
-	// StepOver 下一步，不会进入函数内部
-	StepOver() error
+	// SendToConsole 把content原样写入用户程序的标准输入，eof为true时在之后发送EOF
+	SendToConsole(content string, eof bool) error
+	// StepOver 下一步，不会进入函数内部，threadId为0时使用当前线程
+	// granularity为instruction时按机器指令单步执行
+	StepOver(threadId int, granularity dap.SteppingGranularity) error
 	// StepIn 下一步，会进入函数内部
-	StepIn() error
+	StepIn(threadId int, granularity dap.SteppingGranularity) error
 	// StepOut 单步退出
-	StepOut() error
+	StepOut(threadId int) error
 	// Continue 忽略继续执行
-	Continue() error
-	// SetBreakpoints 设置断点
-	SetBreakpoints(dap.Source, []dap.SourceBreakpoint) error
-	// GetStackTrace 获取栈帧
-	GetStackTrace() ([]dap.StackFrame, error)
+	Continue(threadId int) error
+	// Pause 暂停正在运行的程序
+	Pause() error
+	// StepBack 反向单步执行，需要调试器开启了执行记录
+	StepBack(threadId int) error
+	// ReverseContinue 反向执行，直到遇到断点或者到达执行记录的起点
+	ReverseContinue(threadId int) error
+	// SetBreakpoints 设置断点，返回每个断点的设置结果，断点条件不合法时返回未验证的断点
+	SetBreakpoints(dap.Source, []dap.SourceBreakpoint) ([]dap.Breakpoint, error)
+	// SetFunctionBreakpoints 设置函数断点，会替换之前设置的所有函数断点
+	SetFunctionBreakpoints([]dap.FunctionBreakpoint) ([]dap.Breakpoint, error)
+	// DataBreakpointInfo 获取变量的数据断点信息，reference为0时name是frameId栈帧中的表达式
+	DataBreakpointInfo(reference int, name string, frameId int) (*dap.DataBreakpointInfoResponseBody, error)
+	// SetDataBreakpoints 设置数据断点，会替换之前设置的所有数据断点
+	SetDataBreakpoints([]dap.DataBreakpoint) ([]dap.Breakpoint, error)
+	// SetExceptionBreakpoints 设置开启的异常断点过滤器，程序收到对应的信号时停止
+	SetExceptionBreakpoints(filters []string) error
+	// ExceptionInfo 获取程序最近一次因为信号停止的异常信息
+	ExceptionInfo(threadId int) (*dap.ExceptionInfoResponseBody, error)
+	// GetThreads 获取线程列表
+	GetThreads() ([]dap.Thread, error)
+	// GetStackTrace 获取线程的栈帧，threadId为0时使用当前线程
+	GetStackTrace(threadId int) ([]dap.StackFrame, error)
+	// Disassemble 反汇编memoryReference+offset地址附近的指令，超出范围的部分使用无效指令填充
+	Disassemble(memoryReference string, offset int, instructionOffset int, instructionCount int) ([]dap.DisassembledInstruction, error)
+	// ReadMemory 读取memoryReference+offset开始的count个字节，不可读的部分计入UnreadableBytes
+	ReadMemory(memoryReference string, offset int, count int) (*dap.ReadMemoryResponseBody, error)
+	// WriteMemory 把data写入memoryReference+offset开始的内存，返回写入的字节数
+	WriteMemory(memoryReference string, offset int, data []byte) (int, error)
 	// GetScopes 获取scopes
 	GetScopes(frameId int) ([]dap.Scope, error)
 	// GetVariables 查看引用的值
 	GetVariables(reference int) ([]dap.Variable, error)
+	// Evaluate 在栈帧frameId中计算表达式，context为watch、hover、repl等
+	// 返回的变量如果是结构体或者指针，会设置VariablesReference，可以通过GetVariables展开
+	Evaluate(expression string, frameId int, context string) (*dap.Variable, error)
+	// SetVariable 修改引用reference下名称为name的变量的值，返回修改以后的变量
+	SetVariable(reference int, name string, value string) (*dap.Variable, error)
+	// SetExpression 修改栈帧frameId中表达式的值，返回修改以后的变量
+	SetExpression(expression string, value string, frameId int) (*dap.Variable, error)
 	// Terminate 终止调试
 	// 调用完该命令以后可以重新Launch
 	Terminate() error
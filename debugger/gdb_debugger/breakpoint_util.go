@@ -0,0 +1,193 @@
+package gdb_debugger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-dap"
+)
+
+// breakpointInfo 记录一个gdb断点对应的用户断点信息
+type breakpointInfo struct {
+	number string
+	source string
+	// requestLine 用户设置断点的行
+	requestLine int
+	// line 断点实际所在的行，gdb可能会把断点移动到下一个有代码的行
+	line int
+	// verified 断点是否已经生效，pending的断点在位置加载以后生效
+	verified     bool
+	condition    string
+	hitCondition *hitCondition
+	// logMessage 不为空时该断点是日志断点，命中时输出日志后继续执行
+	logMessage string
+	// hits 断点命中(条件满足)的次数
+	hits int
+}
+
+// sameAs 判断是否和用户设置的断点相同，相同的断点可以保留gdb断点以及命中次数
+func (b *breakpointInfo) sameAs(bp dap.SourceBreakpoint, hit *hitCondition) bool {
+	if b.requestLine != bp.Line || b.condition != bp.Condition || b.logMessage != bp.LogMessage {
+		return false
+	}
+	if b.hitCondition == nil || hit == nil {
+		return b.hitCondition == hit
+	}
+	return *b.hitCondition == *hit
+}
+
+// toBreakpoint 转换成返回给用户的断点
+func (b *breakpointInfo) toBreakpoint(source *dap.Source) dap.Breakpoint {
+	breakpoint := dap.Breakpoint{
+		Verified: b.verified,
+		Line:     b.line,
+		Source:   source,
+	}
+	breakpoint.Id, _ = strconv.Atoi(b.number)
+	if !b.verified {
+		breakpoint.Message = "breakpoint pending, it will be verified once the location is loaded"
+	}
+	return breakpoint
+}
+
+// hitCondition 断点命中次数条件，例如 ">= 5"、"% 3"
+type hitCondition struct {
+	op    string
+	value int
+}
+
+var hitConditionRegexp = regexp.MustCompile(`^\s*(==|!=|>=|<=|>|<|%)?\s*(\d+)\s*$`)
+
+// parseHitCondition 解析命中次数条件
+// 支持 ==、!=、>、>=、<、<=、% 运算符，只有数字时等同于 ==
+func parseHitCondition(condition string) (*hitCondition, error) {
+	if condition == "" {
+		return nil, nil
+	}
+	match := hitConditionRegexp.FindStringSubmatch(condition)
+	if match == nil {
+		return nil, fmt.Errorf("invalid hit condition: %s", condition)
+	}
+	op := match[1]
+	if op == "" {
+		op = "=="
+	}
+	value, err := strconv.Atoi(match[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hit condition: %s", condition)
+	}
+	if op == "%" && value == 0 {
+		return nil, fmt.Errorf("invalid hit condition: %s, modulo by zero", condition)
+	}
+	return &hitCondition{op: op, value: value}, nil
+}
+
+// satisfied 判断第hits次命中时是否满足条件
+func (h *hitCondition) satisfied(hits int) bool {
+	switch h.op {
+	case "==":
+		return hits == h.value
+	case "!=":
+		return hits != h.value
+	case ">":
+		return hits > h.value
+	case ">=":
+		return hits >= h.value
+	case "<":
+		return hits < h.value
+	case "<=":
+		return hits <= h.value
+	case "%":
+		return hits%h.value == 0
+	}
+	return true
+}
+
+// interpolateLogMessage 替换日志断点消息中的{expr}表达式
+// 表达式的值通过evaluate获取，{{和}}分别表示字面量{和}
+func interpolateLogMessage(message string, evaluate func(expression string) string) string {
+	var builder strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		switch {
+		case c == '{' && i+1 < len(message) && message[i+1] == '{':
+			builder.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(message) && message[i+1] == '}':
+			builder.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(message[i+1:], '}')
+			if end == -1 {
+				builder.WriteString(message[i:])
+				return builder.String()
+			}
+			expression := strings.TrimSpace(message[i+1 : i+1+end])
+			builder.WriteString(evaluate(expression))
+			i += end + 1
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// functionDefined 判断语法解析得到的函数列表中是否有名称为name的函数
+// name不带类名或者命名空间时，和函数限定名的最后一段比较
+func functionDefined(name string, functions []FunctionInfo) bool {
+	for _, f := range functions {
+		if f.Name == name {
+			return true
+		}
+		if !strings.Contains(name, "::") {
+			if index := strings.LastIndex(f.Name, "::"); index != -1 && f.Name[index+2:] == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// suggestFunctionName 在语法解析得到的函数列表中查找和name最接近的函数名，找不到时返回空字符串
+func suggestFunctionName(name string, functions []FunctionInfo) string {
+	// 只比较限定名的最后一段，比如 Node::insrt 和 insert
+	if index := strings.LastIndex(name, "::"); index != -1 {
+		name = name[index+2:]
+	}
+	suggestion := ""
+	best := len(name)/3 + 2
+	for _, f := range functions {
+		candidate := f.Name
+		if index := strings.LastIndex(candidate, "::"); index != -1 {
+			candidate = candidate[index+2:]
+		}
+		if distance := levenshteinDistance(name, candidate); distance < best {
+			best = distance
+			suggestion = f.Name
+		}
+	}
+	return suggestion
+}
+
+// levenshteinDistance 计算两个字符串的编辑距离
+func levenshteinDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,77 @@
+package gdb_debugger
+
+import (
+	"testing"
+
+	"github.com/google/go-dap"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHitCondition(t *testing.T) {
+	h, err := parseHitCondition(">= 5")
+	assert.Nil(t, err)
+	assert.False(t, h.satisfied(4))
+	assert.True(t, h.satisfied(5))
+	assert.True(t, h.satisfied(6))
+
+	h, err = parseHitCondition("%3")
+	assert.Nil(t, err)
+	assert.False(t, h.satisfied(2))
+	assert.True(t, h.satisfied(3))
+	assert.True(t, h.satisfied(6))
+
+	h, err = parseHitCondition("2")
+	assert.Nil(t, err)
+	assert.False(t, h.satisfied(1))
+	assert.True(t, h.satisfied(2))
+	assert.False(t, h.satisfied(3))
+
+	h, err = parseHitCondition("")
+	assert.Nil(t, err)
+	assert.Nil(t, h)
+
+	_, err = parseHitCondition("> x")
+	assert.NotNil(t, err)
+	_, err = parseHitCondition("% 0")
+	assert.NotNil(t, err)
+}
+
+func TestInterpolateLogMessage(t *testing.T) {
+	values := map[string]string{"i": "3", "arr[i]": "42"}
+	evaluate := func(expression string) string {
+		return values[expression]
+	}
+	assert.Equal(t, "i = 3, arr[i] = 42", interpolateLogMessage("i = {i}, arr[i] = { arr[i] }", evaluate))
+	assert.Equal(t, "{i} = 3", interpolateLogMessage("{{i}} = {i}", evaluate))
+	assert.Equal(t, "unclosed {i", interpolateLogMessage("unclosed {i", evaluate))
+}
+
+func TestSuggestFunctionName(t *testing.T) {
+	functions := []FunctionInfo{{Name: "main"}, {Name: "insertNode"}, {Name: "Node::insert"}}
+	assert.Equal(t, "main", suggestFunctionName("mian", functions))
+	assert.Equal(t, "insertNode", suggestFunctionName("insertnode", functions))
+	assert.Equal(t, "Node::insert", suggestFunctionName("Node::insrt", functions))
+	assert.Equal(t, "", suggestFunctionName("printf", functions))
+}
+
+func TestFunctionDefined(t *testing.T) {
+	functions := []FunctionInfo{{Name: "main"}, {Name: "Node::insert"}}
+	assert.True(t, functionDefined("main", functions))
+	assert.True(t, functionDefined("Node::insert", functions))
+	assert.True(t, functionDefined("insert", functions))
+	assert.False(t, functionDefined("Tree::insert", functions))
+	assert.False(t, functionDefined("mian", functions))
+}
+
+func TestBreakpointInfoSameAs(t *testing.T) {
+	hit, _ := parseHitCondition(">= 2")
+	info := &breakpointInfo{number: "1", requestLine: 10, line: 11, condition: "i > 3", hitCondition: hit}
+
+	same, _ := parseHitCondition(">=2")
+	assert.True(t, info.sameAs(dap.SourceBreakpoint{Line: 10, Condition: "i > 3", HitCondition: ">=2"}, same))
+	assert.False(t, info.sameAs(dap.SourceBreakpoint{Line: 11, Condition: "i > 3"}, same))
+	assert.False(t, info.sameAs(dap.SourceBreakpoint{Line: 10, Condition: "i > 4"}, same))
+	assert.False(t, info.sameAs(dap.SourceBreakpoint{Line: 10, Condition: "i > 3"}, nil))
+	assert.False(t, info.sameAs(dap.SourceBreakpoint{Line: 10, Condition: "i > 3", LogMessage: "i={i}"}, same))
+}
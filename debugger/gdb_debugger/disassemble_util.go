@@ -0,0 +1,94 @@
+package gdb_debugger
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/google/go-dap"
+)
+
+// maxInstructionLength x86指令的最大长度，无法按函数反汇编时用于估算反汇编的地址范围
+const maxInstructionLength = 15
+
+// disassembleRange 估算从address偏移instructionOffset条指令、共instructionCount条指令的地址范围
+// 起始地址不会小于0，结束地址不会小于address
+func disassembleRange(address uint64, instructionOffset int, instructionCount int) (uint64, uint64) {
+	start := address
+	if instructionOffset < 0 {
+		before := uint64(-instructionOffset) * maxInstructionLength
+		if before > address {
+			before = address
+		}
+		start = address - before
+	}
+	end := address
+	if after := instructionOffset + instructionCount; after > 0 {
+		end = address + uint64(after)*maxInstructionLength
+	}
+	return start, end
+}
+
+// sliceInstructions 从地址address对应的指令开始，偏移instructionOffset条指令，截取instructionCount条指令
+// 超出反汇编范围的部分使用无效指令填充，保证返回的指令数量和请求的数量一致
+func sliceInstructions(instructions []dap.DisassembledInstruction, address uint64, instructionOffset int, instructionCount int) []dap.DisassembledInstruction {
+	answer := make([]dap.DisassembledInstruction, 0, instructionCount)
+	if len(instructions) == 0 {
+		for i := 0; i < instructionCount; i++ {
+			answer = append(answer, invalidInstruction(address+uint64(i)))
+		}
+		return answer
+	}
+	// 找到地址所在的指令，地址在所有指令之后时index为指令数量，和在所有指令之前一样按超出范围处理
+	index := len(instructions)
+	for i, instruction := range instructions {
+		if instructionAddress(instruction) >= address {
+			index = i
+			break
+		}
+	}
+	first := instructionAddress(instructions[0])
+	// 超出范围的无效指令从最后一条指令之后开始，地址在所有指令之后时从该地址开始
+	next := instructionAddress(instructions[len(instructions)-1]) + 1
+	if index == len(instructions) {
+		next = address
+	}
+	var preFile string
+	var preLine int
+	for i := 0; i < instructionCount; i++ {
+		j := index + instructionOffset + i
+		if j < 0 {
+			answer = append(answer, invalidInstruction(first-uint64(-j)))
+			continue
+		}
+		if j >= len(instructions) {
+			answer = append(answer, invalidInstruction(next+uint64(j-len(instructions))))
+			continue
+		}
+		instruction := instructions[j]
+		// 源码位置只在发生变化时返回
+		if instruction.Location != nil {
+			if instruction.Location.Path == preFile && instruction.Line == preLine {
+				instruction.Location = nil
+				instruction.Line = 0
+			} else {
+				preFile, preLine = instruction.Location.Path, instruction.Line
+			}
+		}
+		answer = append(answer, instruction)
+	}
+	return answer
+}
+
+// invalidInstruction 创建填充用的无效指令
+func invalidInstruction(address uint64) dap.DisassembledInstruction {
+	return dap.DisassembledInstruction{
+		Address:     fmt.Sprintf("0x%x", address),
+		Instruction: "??",
+	}
+}
+
+// instructionAddress 解析指令的地址
+func instructionAddress(instruction dap.DisassembledInstruction) uint64 {
+	address, _ := strconv.ParseUint(instruction.Address, 0, 64)
+	return address
+}
@@ -0,0 +1,89 @@
+package gdb_debugger
+
+import (
+	"testing"
+
+	"github.com/google/go-dap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSliceInstructions(t *testing.T) {
+	source := &dap.Source{Name: "main.c", Path: "/tmp/main.c"}
+	instructions := []dap.DisassembledInstruction{
+		{Address: "0x1000", Instruction: "push %rbp", Location: source, Line: 3},
+		{Address: "0x1001", Instruction: "mov %rsp,%rbp", Location: source, Line: 3},
+		{Address: "0x1004", Instruction: "movl $0x1,-0x4(%rbp)", Location: source, Line: 4},
+	}
+
+	answer := sliceInstructions(instructions, 0x1001, -2, 5)
+	assert.Equal(t, []dap.DisassembledInstruction{
+		{Address: "0xfff", Instruction: "??"},
+		{Address: "0x1000", Instruction: "push %rbp", Location: source, Line: 3},
+		{Address: "0x1001", Instruction: "mov %rsp,%rbp"},
+		{Address: "0x1004", Instruction: "movl $0x1,-0x4(%rbp)", Location: source, Line: 4},
+		{Address: "0x1005", Instruction: "??"},
+	}, answer)
+
+	// 地址在所有指令之后，从该地址开始填充无效指令
+	answer = sliceInstructions(instructions, 0x2000, 0, 2)
+	assert.Equal(t, []dap.DisassembledInstruction{
+		{Address: "0x2000", Instruction: "??"},
+		{Address: "0x2001", Instruction: "??"},
+	}, answer)
+	answer = sliceInstructions(instructions, 0x2000, -1, 2)
+	assert.Equal(t, "0x1004", answer[0].Address)
+	assert.Equal(t, "0x2000", answer[1].Address)
+	assert.Equal(t, "??", answer[1].Instruction)
+
+	// 从中间开始截取时，第一条指令也需要返回源码位置
+	answer = sliceInstructions(instructions, 0x1001, 0, 1)
+	assert.Equal(t, source, answer[0].Location)
+	assert.Equal(t, 3, answer[0].Line)
+}
+
+func TestParseDisassembleOutput(t *testing.T) {
+	util := NewGDBOutputUtil()
+	instructions := util.ParseDisassembleOutput(map[string]interface{}{
+		"class": "done",
+		"payload": map[string]interface{}{
+			"asm_insns": []interface{}{
+				map[string]interface{}{"src_and_asm_line": map[string]interface{}{
+					"line":     "5",
+					"fullname": "/tmp/main.c",
+					"line_asm_insn": []interface{}{
+						map[string]interface{}{"address": "0x1149", "func-name": "main", "offset": "0", "inst": "push   %rbp", "opcodes": "55"},
+					},
+				}},
+			},
+		},
+	})
+	assert.Equal(t, []dap.DisassembledInstruction{{
+		Address:          "0x1149",
+		InstructionBytes: "55",
+		Instruction:      "push   %rbp",
+		Symbol:           "main+0",
+		Location:         &dap.Source{Name: "main.c", Path: "/tmp/main.c"},
+		Line:             5,
+	}}, instructions)
+}
+
+func TestDisassembleRange(t *testing.T) {
+	start, end := disassembleRange(0x1000, 0, 2)
+	assert.Equal(t, uint64(0x1000), start)
+	assert.Equal(t, uint64(0x1000+2*maxInstructionLength), end)
+
+	// 向前偏移
+	start, end = disassembleRange(0x1000, -2, 5)
+	assert.Equal(t, uint64(0x1000-2*maxInstructionLength), start)
+	assert.Equal(t, uint64(0x1000+3*maxInstructionLength), end)
+
+	// 请求的指令都在地址之前
+	start, end = disassembleRange(0x1000, -10, 5)
+	assert.Equal(t, uint64(0x1000-10*maxInstructionLength), start)
+	assert.Equal(t, uint64(0x1000), end)
+
+	// 起始地址不能小于0
+	start, end = disassembleRange(0x10, -10, 5)
+	assert.Equal(t, uint64(0), start)
+	assert.Equal(t, uint64(0x10), end)
+}
@@ -8,8 +8,12 @@ import (
 	"os/exec"
 	"sync"
 	"syscall"
+	"time"
 )
 
+// EOFChar 写入到用户程序输入中表示EOF的字符(Ctrl-D)
+const EOFChar = 0x04
+
 // Gdb represents a GDB instance. It implements the ReadWriter interface to
 // read/write data from/to the target program's TTY.
 type Gdb struct {
@@ -55,6 +59,11 @@ func NewCustom(gdbCmd []string, onNotification NotificationCallback) (*Gdb, erro
 	if err != nil {
 		return nil, err
 	}
+	// 按行输入，使用户程序可以读到EOF
+	err = enableLineInput(int(ptm.Fd()))
+	if err != nil {
+		return nil, err
+	}
 	// 清除缓存
 	err = syscall.SetNonblock(int(ptm.Fd()), true)
 	if err != nil {
@@ -122,11 +131,21 @@ func (gdb *Gdb) Read(p []byte) (n int, err error) {
 	return gdb.ptm.Read(p)
 }
 
+// SetReadDeadline 设置读取用户程序输出的超时时间，阻塞中的Read也会在超时以后返回
+func (gdb *Gdb) SetReadDeadline(t time.Time) error {
+	return gdb.ptm.SetReadDeadline(t)
+}
+
 // Write writes a number of bytes to the target program's input.
 func (gdb *Gdb) Write(p []byte) (n int, err error) {
 	return gdb.ptm.Write(p)
 }
 
+// TtyName 返回用户程序终端(pty slave)的路径
+func (gdb *Gdb) TtyName() string {
+	return gdb.pts.Name()
+}
+
 // Interrupt sends a signal (SIGINT) to GDB so it can stop the target program
 // and resume the processing of commands.
 func (gdb *Gdb) Interrupt() error {
@@ -0,0 +1,21 @@
+package gdb
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// enableLineInput 在raw模式的基础上开启规范模式，用户程序按行读取输入，
+// 行首的Ctrl-D(VEOF)会让用户程序的read返回0，即读到EOF
+func enableLineInput(fd int) error {
+	var termios syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCGETS, uintptr(unsafe.Pointer(&termios))); errno != 0 {
+		return errno
+	}
+	termios.Lflag |= syscall.ICANON
+	termios.Cc[syscall.VEOF] = EOFChar
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), syscall.TCSETS, uintptr(unsafe.Pointer(&termios))); errno != 0 {
+		return errno
+	}
+	return nil
+}
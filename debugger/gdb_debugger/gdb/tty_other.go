@@ -0,0 +1,8 @@
+//go:build !linux
+
+package gdb
+
+// enableLineInput 非linux系统保持raw模式
+func enableLineInput(fd int) error {
+	return nil
+}
@@ -2,11 +2,15 @@ package gdb_debugger
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
+	"os"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -22,6 +26,14 @@ import (
 
 const (
 	OptionTimeout = time.Second * 10
+	// outputFlushInterval 用户程序输出的发送间隔
+	outputFlushInterval = 50 * time.Millisecond
+	// outputDrainTimeout 程序退出以后，超过该时间没有新的输出就认为输出已经读完
+	outputDrainTimeout = 100 * time.Millisecond
+	// outputDrainWait 程序退出以后等待输出读完的最长时间
+	outputDrainWait = time.Second
+	// parseSourceTimeout 使用语法解析结果时等待解析完成的最长时间
+	parseSourceTimeout = 3 * time.Second
 )
 
 type GDBDebugger struct {
@@ -32,14 +44,25 @@ type GDBDebugger struct {
 	// gdb示例
 	GDB *gdb2.Gdb
 
+	// sourceMap 服务端源文件路径和客户端路径的映射，返回给客户端的源文件都使用客户端的路径
+	sourceMap sourceMap
+
 	// functionInfos 语法解析解析出来的函数内容，局部变量获取需要通过静态代码分析内容获取变量列表
-	functionInfos []FunctionInfo
+	// 解析协程写入以后关闭functionInfosParsed，读取之前需要等待该channel关闭，使用getFunctionInfos读取
+	functionInfos       []FunctionInfo
+	functionInfosParsed chan struct{}
 
 	// 引用工具
 	ReferenceUtil *ReferenceUtil
 
 	// 事件产生时，触发该回调
 	callback NotificationCallback
+	// outputForwarder 转发用户程序的输出
+	outputForwarder *outputForwarder
+	// userOutputDone 读取用户程序输出的协程结束时关闭，程序没有启动时为nil，只在gdb输出协程中读写
+	userOutputDone chan struct{}
+	// outputDraining 程序已经退出，读取协程读完剩余的输出以后结束，读写时使用atomic
+	outputDraining int32
 
 	// 调试的状态管理
 	StatusManager *StatusManager
@@ -48,8 +71,17 @@ type GDBDebugger struct {
 	GdbOutputUtil *GDBOutputUtil
 
 	// 断点记录
-	mutex             sync.RWMutex
-	breakpointNumbers []string
+	mutex sync.RWMutex
+	// sourceBreakpointNumbers 每个源文件中断点的gdb编号，按照用户设置的顺序
+	sourceBreakpointNumbers map[string][]string
+	// functionBreakpointNumbers 函数断点的编号
+	functionBreakpointNumbers []string
+	// dataBreakpointNumbers 数据断点(观察点)的编号
+	dataBreakpointNumbers []string
+	// breakpointInfos gdb断点编号到断点信息的映射，用于判断命中次数条件
+	// gdb输出协程也会读取，所以使用单独的锁，避免和等待gdb响应的mutex互相等待
+	breakpointInfoLock sync.Mutex
+	breakpointInfos    map[string]*breakpointInfo
 
 	initBreakpointCountLock sync.Mutex
 	NotInitBreakpointCount  int
@@ -58,16 +90,46 @@ type GDBDebugger struct {
 	// 由于为了防止stepIn操作会进入系统依赖内部的特殊处理
 	preAction               string // 记录gdb上一个命令
 	skipContinuedEventCount int64  //记录需要跳过continue事件的数量，读写时必须加锁
+
+	// exceptionLock 保护异常断点过滤器以及最近一次异常，gdb输出协程也会读写
+	exceptionLock    sync.Mutex
+	exceptionFilters map[constants.ExceptionFilterType]bool
+	// lastException 程序最近一次因为信号停止的信息，程序继续执行以后清空
+	lastException *StoppedOutput
+
+	// recording gdb执行记录(record full)的状态，开启以后才可以反向调试，读写时使用atomic
+	recording int32
+	// recordStopMessage gdb因为执行记录无法继续而输出的信息，随后的stopped通知据此关闭执行记录
+	// 只在gdb输出协程中读写
+	recordStopMessage string
+	// inferiorPid 用户程序的进程id，gdb输出协程写入，读写时使用atomic
+	inferiorPid int32
 }
 
+// 执行记录的状态，关闭以后不会再次开启
+const (
+	recordNotStarted int32 = iota
+	recordRunning
+	recordStopped
+)
+
 func NewGDBDebugger(languageType constants.LanguageType) *GDBDebugger {
 	d := &GDBDebugger{
-		StatusManager:         NewStatusManager(),
-		breakpointInitChannel: make(chan struct{}, 2),
-		GdbOutputUtil:         NewGDBOutputUtil(),
-		ReferenceUtil:         NewReferenceUtil(),
-		language:              languageType,
+		StatusManager:           NewStatusManager(),
+		breakpointInitChannel:   make(chan struct{}, 2),
+		GdbOutputUtil:           NewGDBOutputUtil(),
+		ReferenceUtil:           NewReferenceUtil(),
+		language:                languageType,
+		breakpointInfos:         map[string]*breakpointInfo{},
+		sourceBreakpointNumbers: map[string][]string{},
+		// 默认在段错误、abort以及算术异常时停止
+		exceptionFilters: map[constants.ExceptionFilterType]bool{
+			constants.SegfaultFilter: true,
+			constants.AbortFilter:    true,
+			constants.FPEFilter:      true,
+		},
 	}
+	d.outputForwarder = newOutputForwarder(d.sendUserOutput, maxUserOutputBytes, maxOutputChunkBytes)
 	return d
 }
 
@@ -75,16 +137,21 @@ func (g *GDBDebugger) Start(option *StartOption) error {
 	// 设置并创建gdb
 	g.callback = option.Callback
 	g.startOption = option
+	g.sourceMap = newSourceMap(option.SourceMap)
 
 	// 异步进行语法解析
+	g.functionInfosParsed = make(chan struct{})
 	if g.startOption.MainCode != "" {
 		go func() {
+			defer close(g.functionInfosParsed)
 			var err error
 			g.functionInfos, err = ParseSourceFile(g.startOption.MainCode)
 			if err != nil {
 				logrus.Errorf("ParseSourceFile fail, err = %v", err)
 			}
 		}()
+	} else {
+		close(g.functionInfosParsed)
 	}
 
 	gd, err := gdb2.New(g.gdbNotificationCallback)
@@ -93,21 +160,75 @@ func (g *GDBDebugger) Start(option *StartOption) error {
 		return err
 	}
 	g.GDB = gd
-	// 创建命令
-	m, _ := g.GDB.Send("file-exec-and-symbols", option.ExecFile)
-	if result, ok := m["class"]; ok && result == "done" {
-		return nil
-	} else {
-		return fmt.Errorf("目标代码加载失败")
+	// 开启异步模式，程序运行时gdb仍然可以处理exec-interrupt等命令
+	if _, err = g.GDB.CheckedSend("gdb-set", "mi-async", "on"); err != nil {
+		log.Printf("Start fail, err = %s\n", err)
+		return err
 	}
+	// gdb默认会停止的信号和默认的异常断点过滤器不一致，启动时按照过滤器设置
+	g.exceptionLock.Lock()
+	filters := g.exceptionFilters
+	g.exceptionLock.Unlock()
+	if err = g.handleSignals(filters); err != nil {
+		log.Printf("Start fail, err = %s\n", err)
+		return err
+	}
+	// 加载目标程序，附加进程时可以不指定程序，gdb会从进程中读取符号
+	if option.ExecFile != "" {
+		m, _ := g.GDB.Send("file-exec-and-symbols", option.ExecFile)
+		if result, ok := m["class"]; !ok || result != "done" {
+			return fmt.Errorf("目标代码加载失败")
+		}
+	}
+	if option.ProcessId != 0 {
+		return g.attach(option.ProcessId)
+	}
+	return g.setInferiorEnvironment(option)
+}
+
+// setInferiorEnvironment 设置用户程序的参数、环境变量以及工作目录
+func (g *GDBDebugger) setInferiorEnvironment(option *StartOption) error {
+	if len(option.Args) != 0 {
+		if _, err := g.GDB.CheckedSend("exec-arguments", option.Args...); err != nil {
+			return fmt.Errorf("设置程序参数失败: %w", err)
+		}
+	}
+	if option.Cwd != "" {
+		if _, err := g.GDB.CheckedSend("environment-cd", option.Cwd); err != nil {
+			return fmt.Errorf("设置工作目录失败: %w", err)
+		}
+	}
+	for key, value := range option.Env {
+		if _, err := g.GDB.CheckedSend("gdb-set", "environment", key+"="+value); err != nil {
+			return fmt.Errorf("设置环境变量失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// attach 附加到已经运行的进程，附加成功以后进程处于暂停状态
+func (g *GDBDebugger) attach(pid int) error {
+	if _, err := g.GDB.CheckedSend("target-attach", strconv.Itoa(pid)); err != nil {
+		return fmt.Errorf("附加进程失败: %w", err)
+	}
+	g.StatusManager.Set(Stopped)
+	return nil
 }
 
 // Run 同步方法，开始运行
+// 如果是附加的进程，进程已经存在，只需要继续执行
 func (g *GDBDebugger) Run() error {
+	if g.startOption.ProcessId != 0 {
+		return g.continue2(0)
+	}
 	var gdbCallback gdb2.AsyncCallback = func(m map[string]interface{}) {
-		gosync.Go(context.Background(), g.processUserInput)
 		// 启动协程读取用户输出
-		gosync.Go(context.Background(), g.processUserOutput)
+		done := make(chan struct{})
+		g.userOutputDone = done
+		gosync.Go(context.Background(), func(ctx context.Context) {
+			g.processUserOutput(ctx, done)
+		})
+		gosync.Go(context.Background(), g.processUserInputState)
 	}
 	// 设置语言
 	_, _ = g.GDB.SendWithTimeout(OptionTimeout, "gdb-set", "language", string(g.language))
@@ -120,117 +241,687 @@ func (g *GDBDebugger) Run() error {
 	return nil
 }
 
-// processUserInput 循环读取用户输入
-func (g *GDBDebugger) processUserInput(ctx context.Context) {
-	var input string
-	for {
-		if g.StatusManager == nil || g.GDB == nil {
-			break
-		}
-		if g.StatusManager.Is(Finish) {
-			break
-		}
+// SendToConsole 把用户输入原样写入用户程序的终端，eof为true时在输入之后发送EOF
+func (g *GDBDebugger) SendToConsole(content string, eof bool) error {
+	if g.GDB == nil || g.StatusManager.Is(Finish) {
+		return errors.New("program is not running")
+	}
+	if _, err := g.GDB.Write(consoleInput(content, eof)); err != nil {
+		logrus.Errorf("SendToConsole fail, err = %s\n", err)
+		return err
+	}
+	return nil
+}
 
-		_, err := fmt.Scanln(&input)
-		if err == nil {
-			if input[len(input)-1] != '\n' {
-				input = input + "\n"
-			}
-			if _, err = g.GDB.Write([]byte(input)); err != nil {
-				logrus.Errorf("processUserInput fail, err = %s\n", err)
-			}
-		}
-		time.Sleep(100 * time.Microsecond)
+// consoleInput 构造写入终端的字节，终端按行输入，
+// 未以换行结尾的内容需要先用一个Ctrl-D提交，再用一个Ctrl-D表示EOF
+func consoleInput(content string, eof bool) []byte {
+	input := []byte(content)
+	if !eof {
+		return input
+	}
+	if len(input) != 0 && input[len(input)-1] != '\n' {
+		input = append(input, gdb2.EOFChar)
 	}
+	return append(input, gdb2.EOFChar)
 }
 
-// processUserOutput 循环处理用户输出
-func (g *GDBDebugger) processUserOutput(ctx context.Context) {
+// processUserOutput 循环读取用户输出，以stdout类型的OutputEvent转发给客户端
+// 用户程序的stdout和stderr都连接到同一个pty，无法区分
+// 结束时关闭outputDone
+func (g *GDBDebugger) processUserOutput(ctx context.Context, outputDone chan struct{}) {
+	defer close(outputDone)
+	done := make(chan struct{})
+	gosync.Go(ctx, func(ctx context.Context) {
+		ticker := time.NewTicker(outputFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				g.outputForwarder.flush(false)
+			}
+		}
+	})
 	b := make([]byte, 1024)
 	for {
+		// 程序已经退出，一段时间内没有新的输出时读取超时，结束读取
+		if atomic.LoadInt32(&g.outputDraining) == 1 {
+			_ = g.GDB.SetReadDeadline(time.Now().Add(outputDrainTimeout))
+		}
 		n, err := g.GDB.Read(b)
+		if n > 0 {
+			g.outputForwarder.write(b[0:n])
+		}
 		if err != nil {
+			close(done)
+			g.outputForwarder.flush(true)
+			return
+		}
+	}
+}
+
+// drainUserOutput 程序退出时调用，通知读取协程读完终端中剩余的输出，并等待读取协程结束
+func (g *GDBDebugger) drainUserOutput() {
+	if g.userOutputDone == nil {
+		return
+	}
+	atomic.StoreInt32(&g.outputDraining, 1)
+	// 读取协程可能阻塞在Read中，设置超时让它返回
+	_ = g.GDB.SetReadDeadline(time.Now().Add(outputDrainTimeout))
+	select {
+	case <-g.userOutputDone:
+	case <-time.After(outputDrainWait):
+		logrus.Errorf("drainUserOutput timeout")
+	}
+}
+
+// processUserInputState 定时检查用户程序是否在等待输入，用户程序退出以后结束
+// 只在程序运行时检查，程序暂停时保留之前的状态
+func (g *GDBDebugger) processUserInputState(ctx context.Context) {
+	watcher := newInputWatcher(g.GDB.TtyName(), g.sendInputState)
+	ticker := time.NewTicker(inputPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if g.StatusManager.Is(Finish) {
+			return
+		}
+		pid := int(atomic.LoadInt32(&g.inferiorPid))
+		if pid == 0 || !g.StatusManager.Is(Running) {
+			continue
+		}
+		if err := watcher.check(pid); err != nil {
+			// 进程已经退出
+			watcher.update(false)
 			return
 		}
-		output := string(b[0:n])
-		fmt.Print(output)
 	}
 }
 
-func (g *GDBDebugger) StepOver() error {
+// sendInputState 通知客户端用户程序开始或者结束等待输入
+func (g *GDBDebugger) sendInputState(waiting bool) {
+	event := constants.InputSatisfiedEvent
+	if waiting {
+		event = constants.InputRequestedEvent
+	}
+	g.callback(NewEvent(0, string(event)))
+}
+
+// sendUserOutput 发送用户程序的输出
+func (g *GDBDebugger) sendUserOutput(output string) {
+	g.callback(&dap.OutputEvent{
+		Event: *NewEvent(0, "output"),
+		Body:  dap.OutputEventBody{Category: "stdout", Output: output},
+	})
+}
+
+// StepOver 单步执行，threadId为0时使用当前线程
+// granularity为instruction时按机器指令单步执行
+func (g *GDBDebugger) StepOver(threadId int, granularity dap.SteppingGranularity) error {
 	if !g.StatusManager.Is(Stopped) {
 		return errors.New("程序运行中，无法执行单步调试")
 	}
-	return g.stepOver()
+	return g.stepOver(threadId, granularity)
 }
 
-func (g *GDBDebugger) stepOver() error {
+func (g *GDBDebugger) stepOver(threadId int, granularity dap.SteppingGranularity) error {
 	g.preAction = "exec-next"
-	err := g.GDB.SendAsync(func(obj map[string]interface{}) {}, "exec-next")
+	if granularity == "instruction" {
+		g.preAction = "exec-next-instruction"
+	}
+	err := g.GDB.SendAsync(func(obj map[string]interface{}) {}, g.preAction, threadOption(threadId)...)
 	return err
 }
 
-func (g *GDBDebugger) StepIn() error {
+func (g *GDBDebugger) StepIn(threadId int, granularity dap.SteppingGranularity) error {
 	if !g.StatusManager.Is(Stopped) {
 		return errors.New("程序运行中，无法执行单步调试")
 	}
-	return g.stopIn()
+	return g.stopIn(threadId, granularity)
 }
 
-func (g *GDBDebugger) stopIn() error {
+func (g *GDBDebugger) stopIn(threadId int, granularity dap.SteppingGranularity) error {
 	g.preAction = "exec-step"
-	err := g.GDB.SendAsync(func(obj map[string]interface{}) {}, "exec-step")
+	if granularity == "instruction" {
+		g.preAction = "exec-step-instruction"
+	}
+	err := g.GDB.SendAsync(func(obj map[string]interface{}) {}, g.preAction, threadOption(threadId)...)
 	return err
 }
 
-func (g *GDBDebugger) StepOut() error {
+func (g *GDBDebugger) StepOut(threadId int) error {
 	if !g.StatusManager.Is(Stopped) {
 		return errors.New("程序运行中，无法执行单步调试")
 	}
-	return g.stopOut()
+	return g.stopOut(threadId)
 }
 
-func (g *GDBDebugger) stopOut() error {
+func (g *GDBDebugger) stopOut(threadId int) error {
 	g.preAction = "exec-finish"
-	err := g.GDB.SendAsync(func(obj map[string]interface{}) {}, "exec-finish")
+	err := g.GDB.SendAsync(func(obj map[string]interface{}) {}, "exec-finish", threadOption(threadId)...)
 	return err
 }
 
-func (g *GDBDebugger) Continue() error {
+// Continue 继续执行，all-stop模式下所有线程都会恢复执行
+func (g *GDBDebugger) Continue(threadId int) error {
 	if !g.StatusManager.Is(Stopped) {
 		return errors.New("程序运行中，无法执行continue")
 	}
-	return g.continue2()
+	return g.continue2(threadId)
 }
 
-func (g *GDBDebugger) continue2() error {
+func (g *GDBDebugger) continue2(threadId int) error {
 	g.preAction = "exec-continue"
-	err := g.GDB.SendAsync(func(obj map[string]interface{}) {}, "exec-continue")
+	err := g.GDB.SendAsync(func(obj map[string]interface{}) {}, "exec-continue", threadOption(threadId)...)
+	return err
+}
+
+// Pause 暂停正在运行的程序，程序停止以后发送reason为pause的stopped事件
+func (g *GDBDebugger) Pause() error {
+	if !g.StatusManager.Is(Running) {
+		return errors.New("程序未运行，无法暂停")
+	}
+	_, err := g.GDB.CheckedSend("exec-interrupt")
 	return err
 }
 
-func (g *GDBDebugger) SetBreakpoints(source dap.Source, breakpoints []dap.SourceBreakpoint) error {
+// StepBack 反向单步执行，回到上一行，不会进入函数内部
+func (g *GDBDebugger) StepBack(threadId int) error {
+	return g.reverse("exec-next", threadId)
+}
+
+// ReverseStepIn 反向单步执行，会进入函数内部
+func (g *GDBDebugger) ReverseStepIn(threadId int) error {
+	return g.reverse("exec-step", threadId)
+}
+
+// ReverseContinue 反向执行，直到遇到断点或者到达执行记录的起点
+func (g *GDBDebugger) ReverseContinue(threadId int) error {
+	return g.reverse("exec-continue", threadId)
+}
+
+// reverse 以--reverse的方式执行operation
+func (g *GDBDebugger) reverse(operation string, threadId int) error {
+	if !g.StatusManager.Is(Stopped) {
+		return errors.New("程序运行中，无法反向执行")
+	}
+	// 执行记录会让程序变慢，只在客户端要求时开启，不会在反向执行时自动开启
+	if !g.startOption.Record {
+		return errors.New("reverse debugging requires launch `record: true`")
+	}
+	if atomic.LoadInt32(&g.recording) != recordRunning {
+		return errors.New("执行记录已关闭，无法反向执行")
+	}
+	g.preAction = operation
+	args := append(threadOption(threadId), "--reverse")
+	return g.GDB.SendAsync(func(obj map[string]interface{}) {}, operation, args...)
+}
+
+// startRecord 开启gdb的执行记录，需要在程序启动以后才能开启
+// 在gdb的输出协程中调用，所以只能使用异步命令，gdb会按顺序处理后续的命令
+func (g *GDBDebugger) startRecord() {
+	if !atomic.CompareAndSwapInt32(&g.recording, recordNotStarted, recordRunning) {
+		return
+	}
+	// 记录满了以后自动删除最早的记录，而不是停止程序询问用户
+	_ = g.GDB.SendAsync(func(obj map[string]interface{}) {}, "gdb-set", "record", "full", "stop-at-limit", "off")
+	err := g.GDB.SendAsync(func(m map[string]interface{}) {
+		if msg := g.GdbOutputUtil.GetErrorMessage(m); msg != "" {
+			atomic.StoreInt32(&g.recording, recordStopped)
+			logrus.Errorf("startRecord fail, err = %s", msg)
+		}
+	}, "interpreter-exec", "console", "record full")
+	if err != nil {
+		atomic.StoreInt32(&g.recording, recordStopped)
+		logrus.Errorf("startRecord fail, err = %s", err)
+	}
+}
+
+// stopRecord 关闭执行记录，程序因为执行记录不支持的指令或者即将退出而停止时调用
+// reason为gdb输出的原因，会在控制台提示用户之后无法反向调试
+func (g *GDBDebugger) stopRecord(reason string) {
+	if !atomic.CompareAndSwapInt32(&g.recording, recordRunning, recordStopped) {
+		return
+	}
+	err := g.GDB.SendAsync(func(obj map[string]interface{}) {}, "interpreter-exec", "console", "record stop")
+	if err != nil {
+		logrus.Errorf("stopRecord fail, err = %s", err)
+	}
+	g.callback(&dap.OutputEvent{
+		Event: *NewEvent(0, "output"),
+		Body: dap.OutputEventBody{
+			Category: "console",
+			Output:   fmt.Sprintf("执行记录已关闭，之后无法反向调试: %s\n", reason),
+		},
+	})
+}
+
+// processConsoleOutput 处理gdb的控制台输出，记录执行记录无法继续的原因
+// 执行记录遇到不支持的指令，或者程序即将退出时，gdb会先输出原因再停止程序
+func (g *GDBDebugger) processConsoleOutput(text string) {
+	if atomic.LoadInt32(&g.recording) != recordRunning {
+		return
+	}
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "Process record") || strings.HasPrefix(text, "The next instruction is syscall exit") {
+		g.recordStopMessage = text
+	}
+}
+
+// threadOption 构建指定线程的gdb命令参数，threadId为0时不指定线程
+func threadOption(threadId int) []string {
+	if threadId == 0 {
+		return nil
+	}
+	return []string{"--thread", strconv.Itoa(threadId)}
+}
+
+// SetBreakpoints 设置源文件的断点，返回每个断点的设置结果
+// 每个源文件单独记录断点，和之前相同的断点保留原来的gdb断点以及命中次数，只删除和新增变化的断点
+// 断点条件通过break-insert -c交给gdb处理，命中次数条件在程序停止时由调试器判断
+func (g *GDBDebugger) SetBreakpoints(source dap.Source, breakpoints []dap.SourceBreakpoint) ([]dap.Breakpoint, error) {
 	g.mutex.Lock()
-	// 删除原来的所有断点
-	err := g.removeBreakpoints(g.breakpointNumbers)
+	defer g.mutex.Unlock()
+	path := source.Path
+	if path == "" {
+		path = source.Name
+	}
+	// gdb只认识编译时的路径
+	path = g.sourceMap.serverPath(path)
+
+	// 找到和之前相同的断点，其余的旧断点需要删除
+	hitConditions := make([]*hitCondition, len(breakpoints))
+	hitErrors := make([]error, len(breakpoints))
+	reused := make([]*breakpointInfo, len(breakpoints))
+	kept := map[string]bool{}
+	g.breakpointInfoLock.Lock()
+	for i, bp := range breakpoints {
+		hitConditions[i], hitErrors[i] = parseHitCondition(bp.HitCondition)
+		if hitErrors[i] != nil {
+			continue
+		}
+		for _, number := range g.sourceBreakpointNumbers[path] {
+			info, ok := g.breakpointInfos[number]
+			if ok && !kept[number] && info.sameAs(bp, hitConditions[i]) {
+				reused[i] = info
+				kept[number] = true
+				break
+			}
+		}
+	}
+	var removed []string
+	for _, number := range g.sourceBreakpointNumbers[path] {
+		if !kept[number] {
+			removed = append(removed, number)
+			delete(g.breakpointInfos, number)
+		}
+	}
+	g.breakpointInfoLock.Unlock()
+	if err := g.removeBreakpoints(removed); err != nil {
+		return nil, err
+	}
+
+	var numbers []string
+	answer := make([]dap.Breakpoint, 0, len(breakpoints))
+	for i, bp := range breakpoints {
+		if reused[i] != nil {
+			numbers = append(numbers, reused[i].number)
+			g.breakpointInfoLock.Lock()
+			answer = append(answer, reused[i].toBreakpoint(&source))
+			g.breakpointInfoLock.Unlock()
+			continue
+		}
+		breakpoint := dap.Breakpoint{Line: bp.Line, Source: &source}
+		if hitErrors[i] != nil {
+			breakpoint.Message = hitErrors[i].Error()
+			answer = append(answer, breakpoint)
+			continue
+		}
+		// -f 位置无法解析时创建pending断点，而不是直接失败
+		args := []string{"-f"}
+		if bp.Condition != "" {
+			args = append(args, "-c", bp.Condition)
+		}
+		args = append(args, path+":"+strconv.Itoa(bp.Line))
+		result, err := g.GDB.SendWithTimeout(OptionTimeout, "break-insert", args...)
+		if err != nil {
+			breakpoint.Message = err.Error()
+			answer = append(answer, breakpoint)
+			continue
+		}
+		output, success := g.GdbOutputUtil.ParseBreakpointOutput(result)
+		if !success {
+			breakpoint.Message = g.GdbOutputUtil.GetErrorMessage(result)
+			answer = append(answer, breakpoint)
+			continue
+		}
+		numbers = append(numbers, output.Number)
+		info := &breakpointInfo{
+			number:       output.Number,
+			source:       path,
+			requestLine:  bp.Line,
+			line:         bp.Line,
+			verified:     !output.Pending,
+			condition:    bp.Condition,
+			hitCondition: hitConditions[i],
+			logMessage:   bp.LogMessage,
+		}
+		if output.Line != 0 {
+			info.line = output.Line
+		}
+		g.breakpointInfoLock.Lock()
+		g.breakpointInfos[output.Number] = info
+		g.breakpointInfoLock.Unlock()
+		answer = append(answer, info.toBreakpoint(&source))
+	}
+	if len(numbers) == 0 {
+		delete(g.sourceBreakpointNumbers, path)
+	} else {
+		g.sourceBreakpointNumbers[path] = numbers
+	}
+	return answer, nil
+}
+
+// SetFunctionBreakpoints 设置函数断点，每次请求都会替换掉之前的所有函数断点
+// 函数名先和语法解析得到的函数列表比较，用户代码和gdb都找不到该函数时给出最接近的函数名
+func (g *GDBDebugger) SetFunctionBreakpoints(breakpoints []dap.FunctionBreakpoint) ([]dap.Breakpoint, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := g.removeBreakpoints(g.functionBreakpointNumbers); err != nil {
+		return nil, err
+	}
+	g.breakpointInfoLock.Lock()
+	for _, number := range g.functionBreakpointNumbers {
+		delete(g.breakpointInfos, number)
+	}
+	g.breakpointInfoLock.Unlock()
+	g.functionBreakpointNumbers = nil
+
+	// 刚启动调试时语法解析可能还没有完成
+	functionInfos := g.getFunctionInfos()
+	answer := make([]dap.Breakpoint, 0, len(breakpoints))
+	for _, bp := range breakpoints {
+		breakpoint := dap.Breakpoint{}
+		hit, err := parseHitCondition(bp.HitCondition)
+		if err != nil {
+			breakpoint.Message = err.Error()
+			answer = append(answer, breakpoint)
+			continue
+		}
+		// std::sort<...> 表示函数的所有模板实例，gdb会匹配不带模板参数的函数名
+		name := strings.TrimSuffix(strings.TrimSpace(bp.Name), "<...>")
+		// 先根据语法解析得到的函数列表检查函数名，不在列表中的可能是库函数，仍然交给gdb解析
+		defined := len(functionInfos) == 0 || functionDefined(name, functionInfos)
+		var args []string
+		if bp.Condition != "" {
+			args = append(args, "-c", bp.Condition)
+		}
+		args = append(args, "--function", name)
+		result, err := g.GDB.SendWithTimeout(OptionTimeout, "break-insert", args...)
+		if err != nil {
+			breakpoint.Message = err.Error()
+			answer = append(answer, breakpoint)
+			continue
+		}
+		output, success := g.GdbOutputUtil.ParseBreakpointOutput(result)
+		if !success {
+			breakpoint.Message = g.GdbOutputUtil.GetErrorMessage(result)
+			// 用户代码和gdb都找不到该函数，给出最接近的函数名
+			if !defined {
+				breakpoint.Message = fmt.Sprintf("function %q not found", name)
+				if suggestion := suggestFunctionName(name, functionInfos); suggestion != "" {
+					breakpoint.Message = fmt.Sprintf("function %q not found, did you mean %q?", name, suggestion)
+				}
+			}
+			answer = append(answer, breakpoint)
+			continue
+		}
+		g.functionBreakpointNumbers = append(g.functionBreakpointNumbers, output.Number)
+		g.breakpointInfoLock.Lock()
+		g.breakpointInfos[output.Number] = &breakpointInfo{
+			number:       output.Number,
+			source:       output.File,
+			line:         output.Line,
+			verified:     true,
+			condition:    bp.Condition,
+			hitCondition: hit,
+		}
+		g.breakpointInfoLock.Unlock()
+		breakpoint.Id, _ = strconv.Atoi(output.Number)
+		breakpoint.Verified = true
+		breakpoint.Line = output.Line
+		if output.File != "" {
+			breakpoint.Source = g.sourceMap.clientSource(output.File)
+		}
+		answer = append(answer, breakpoint)
+	}
+	return answer, nil
+}
+
+// DataBreakpointInfo 获取变量的数据断点信息，dataId由变量引用和变量名组成
+// reference为0时name是栈帧frameId中的表达式
+func (g *GDBDebugger) DataBreakpointInfo(reference int, name string, frameId int) (*dap.DataBreakpointInfoResponseBody, error) {
+	if reference == 0 {
+		reference = g.ReferenceUtil.GetScopesReference(frameId)
+	}
+	answer := &dap.DataBreakpointInfoResponseBody{
+		Description: name,
+		AccessTypes: []dap.DataBreakpointAccessType{"write", "read", "readWrite"},
+	}
+	if !g.StatusManager.Is(Stopped) {
+		answer.Description = "程序未暂停，无法设置数据断点"
+		return answer, nil
+	}
+	_, expression, err := g.ResolveVariableExpression(reference, name)
 	if err != nil {
-		return err
+		answer.Description = err.Error()
+		return answer, nil
+	}
+	answer.DataId = fmt.Sprintf("%d/%s", reference, name)
+	answer.Description = expression
+	return answer, nil
+}
+
+// SetDataBreakpoints 设置数据断点(观察点)，每次请求都会替换掉之前的所有数据断点
+func (g *GDBDebugger) SetDataBreakpoints(breakpoints []dap.DataBreakpoint) ([]dap.Breakpoint, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if err := g.removeBreakpoints(g.dataBreakpointNumbers); err != nil {
+		return nil, err
 	}
+	g.breakpointInfoLock.Lock()
+	for _, number := range g.dataBreakpointNumbers {
+		delete(g.breakpointInfos, number)
+	}
+	g.breakpointInfoLock.Unlock()
+	g.dataBreakpointNumbers = nil
+
+	answer := make([]dap.Breakpoint, 0, len(breakpoints))
 	for _, bp := range breakpoints {
-		result, err := g.GDB.SendWithTimeout(OptionTimeout, "break-insert", source.Path+":"+strconv.Itoa(bp.Line))
+		breakpoint := dap.Breakpoint{}
+		number, err := g.insertWatchpoint(bp)
 		if err != nil {
+			breakpoint.Message = err.Error()
+			answer = append(answer, breakpoint)
 			continue
-		} else {
-			success, number := g.GdbOutputUtil.ParseAddBreakpointOutput(result)
-			if success {
-				g.breakpointNumbers = append(g.breakpointNumbers, number)
+		}
+		g.dataBreakpointNumbers = append(g.dataBreakpointNumbers, number)
+		breakpoint.Verified = true
+		breakpoint.Id, _ = strconv.Atoi(number)
+		answer = append(answer, breakpoint)
+	}
+	return answer, nil
+}
+
+// insertWatchpoint 根据dataId插入观察点，返回观察点编号
+func (g *GDBDebugger) insertWatchpoint(bp dap.DataBreakpoint) (string, error) {
+	hit, err := parseHitCondition(bp.HitCondition)
+	if err != nil {
+		return "", err
+	}
+	reference, name, found := strings.Cut(bp.DataId, "/")
+	if !found {
+		return "", fmt.Errorf("invalid dataId: %s", bp.DataId)
+	}
+	ref, err := strconv.Atoi(reference)
+	if err != nil {
+		return "", fmt.Errorf("invalid dataId: %s", bp.DataId)
+	}
+	frameId, expression, err := g.ResolveVariableExpression(ref, name)
+	if err != nil {
+		return "", err
+	}
+	// 局部变量需要在对应的栈帧中设置观察点
+	if frameId != "" {
+		if _, err = g.GDB.SendWithTimeout(OptionTimeout, "stack-select-frame", frameId); err != nil {
+			return "", err
+		}
+	}
+	var args []string
+	switch bp.AccessType {
+	case "read":
+		args = append(args, "-r")
+	case "readWrite":
+		args = append(args, "-a")
+	}
+	args = append(args, expression)
+	result, err := g.GDB.SendWithTimeout(OptionTimeout, "break-watch", args...)
+	if err != nil {
+		return "", err
+	}
+	number, success := g.GdbOutputUtil.ParseWatchpointOutput(result)
+	if !success {
+		return "", errors.New(g.GdbOutputUtil.GetErrorMessage(result))
+	}
+	if bp.Condition != "" {
+		result, err = g.GDB.SendWithTimeout(OptionTimeout, "break-condition", number, bp.Condition)
+		if err == nil {
+			if msg := g.GdbOutputUtil.GetErrorMessage(result); msg != "" {
+				err = errors.New(msg)
 			}
 		}
+		if err != nil {
+			_ = g.removeBreakpoints([]string{number})
+			return "", err
+		}
+	}
+	g.breakpointInfoLock.Lock()
+	g.breakpointInfos[number] = &breakpointInfo{
+		number:       number,
+		condition:    bp.Condition,
+		hitCondition: hit,
 	}
-	g.mutex.Unlock()
+	g.breakpointInfoLock.Unlock()
+	return number, nil
+}
+
+// SetExceptionBreakpoints 设置开启的异常断点过滤器，会替换之前的设置
+func (g *GDBDebugger) SetExceptionBreakpoints(filters []string) error {
+	exceptionFilters := make(map[constants.ExceptionFilterType]bool, len(filters))
+	for _, filter := range filters {
+		switch constants.ExceptionFilterType(filter) {
+		case constants.SegfaultFilter, constants.AbortFilter, constants.FPEFilter, constants.AllSignalsFilter:
+			exceptionFilters[constants.ExceptionFilterType(filter)] = true
+		default:
+			return fmt.Errorf("unknown exception filter %s", filter)
+		}
+	}
+	if err := g.handleSignals(exceptionFilters); err != nil {
+		return err
+	}
+	g.exceptionLock.Lock()
+	g.exceptionFilters = exceptionFilters
+	g.exceptionLock.Unlock()
 	return nil
 }
 
+// handleSignals 设置gdb收到信号时的处理方式，开启了异常断点的信号才停止程序
+// 没有开启的信号不会停止程序，避免停止以后再静默继续执行
+func (g *GDBDebugger) handleSignals(filters map[constants.ExceptionFilterType]bool) error {
+	for _, command := range signalHandleCommands(filters) {
+		m, err := g.sendWithTimeOut(OptionTimeout, "interpreter-exec", "console", command)
+		if err != nil {
+			return err
+		}
+		if msg := g.GdbOutputUtil.GetErrorMessage(m); msg != "" {
+			return fmt.Errorf("设置信号处理方式失败: %s", msg)
+		}
+	}
+	return nil
+}
+
+// ExceptionInfo 获取程序最近一次因为信号停止的异常信息
+// 包括信号名称、含义、出错的内存地址以及导致异常的用户代码栈帧
+func (g *GDBDebugger) ExceptionInfo(threadId int) (*dap.ExceptionInfoResponseBody, error) {
+	g.exceptionLock.Lock()
+	exception := g.lastException
+	g.exceptionLock.Unlock()
+	if exception == nil {
+		return nil, errors.New("程序没有发生异常")
+	}
+	description := exception.signalMeaning
+	if hasFaultingAddress(exception.signalName) {
+		m, err := g.sendWithTimeOut(OptionTimeout, "data-evaluate-expression", "$_siginfo._sifields._sigfault.si_addr")
+		if err == nil && g.GdbOutputUtil.GetErrorMessage(m) == "" {
+			payload := g.GdbOutputUtil.GetInterfaceFromMap(m, "payload")
+			address := g.GdbOutputUtil.ConvertValueToAddress(g.GdbOutputUtil.GetStringFromMap(payload, "value"))
+			description = fmt.Sprintf("%s at address %s", description, address)
+		}
+	}
+	answer := &dap.ExceptionInfoResponseBody{
+		ExceptionId: exception.signalName,
+		BreakMode:   "always",
+		Details: &dap.ExceptionDetails{
+			Message:  exception.signalMeaning,
+			TypeName: exception.signalName,
+		},
+	}
+	// 信号可能发生在库函数中，找到调用链上第一个用户代码的栈帧
+	if frames, err := g.GetStackTrace(threadId); err == nil {
+		if frame := findUserFrame(frames, g.sourceMap); frame != nil {
+			description = fmt.Sprintf("%s, in %s (%s:%d)", description, frame.Name, frame.Source.Name, frame.Line)
+			answer.Details.StackTrace = fmt.Sprintf("at %s (%s:%d)", frame.Name, frame.Source.Path, frame.Line)
+		}
+	}
+	answer.Description = description
+	return answer, nil
+}
+
+// findUserFrame 查找第一个用户代码的栈帧，用户代码的源文件存在于本地，库函数的源文件一般不存在
+// 栈帧使用客户端的路径，需要转换成服务端的路径再检查
+func findUserFrame(frames []dap.StackFrame, sources sourceMap) *dap.StackFrame {
+	for i, frame := range frames {
+		if frame.Source == nil || frame.Source.Path == "" {
+			continue
+		}
+		if _, err := os.Stat(sources.serverPath(frame.Source.Path)); err == nil {
+			return &frames[i]
+		}
+	}
+	return nil
+}
+
+// ResolveVariableExpression 根据变量引用和变量名称，获取变量的表达式以及需要切换到的栈帧
+// 全局变量不需要切换栈帧，frameId为空
+func (g *GDBDebugger) ResolveVariableExpression(reference int, name string) (string, string, error) {
+	if g.ReferenceUtil.CheckIsGlobalScope(reference) {
+		return "", name, nil
+	}
+	if g.ReferenceUtil.CheckIsLocalScope(reference) {
+		return strconv.Itoa(g.ReferenceUtil.GetFrameIDByLocalReference(reference)), name, nil
+	}
+	refStruct, err := g.ReferenceUtil.ParseVariableReference(reference)
+	if err != nil {
+		return "", "", err
+	}
+	var frameId string
+	if refStruct.Type == StructType {
+		frameId = refStruct.FrameId
+	}
+	return frameId, g.GetExport(GetFieldReferenceStruct(refStruct, name)), nil
+}
+
 func (g *GDBDebugger) removeBreakpoints(numbers []string) error {
 	for _, number := range numbers {
 		var callback gdb2.AsyncCallback = func(m map[string]interface{}) {}
@@ -242,7 +933,7 @@ func (g *GDBDebugger) removeBreakpoints(numbers []string) error {
 }
 
 func (g *GDBDebugger) Terminate() error {
-	if g.StatusManager.Is(Finish) {
+	if g.GDB == nil || g.StatusManager.Is(Finish) {
 		return nil
 	}
 	// 发送终端给程序
@@ -260,16 +951,30 @@ func (g *GDBDebugger) Terminate() error {
 	return nil
 }
 
-func (g *GDBDebugger) GetStackTrace() ([]dap.StackFrame, error) {
+// GetStackTrace 获取线程的栈帧，threadId为0时使用当前线程
+// 获取以后会选中该线程，后续的scopes和variables请求都在该线程中进行
+func (g *GDBDebugger) GetStackTrace(threadId int) ([]dap.StackFrame, error) {
 	if !g.StatusManager.Is(Stopped) {
 		return nil, errors.New("程序未暂停无法获取栈帧信息")
 	}
+	if threadId != 0 {
+		if _, err := g.sendWithTimeOut(OptionTimeout, "thread-select", strconv.Itoa(threadId)); err != nil {
+			log.Printf("GetStackTrace fail, err = %s\n", err)
+			return nil, err
+		}
+	}
 	m, err := g.sendWithTimeOut(OptionTimeout, "stack-list-frames")
 	if err != nil {
 		log.Printf("GetStackTrace fail, err = %s\n", err)
 		return nil, err
 	}
-	return g.GdbOutputUtil.ParseStackTraceOutput(m), nil
+	frames := g.GdbOutputUtil.ParseStackTraceOutput(m)
+	for i := range frames {
+		if frames[i].Source != nil {
+			frames[i].Source = g.sourceMap.clientSource(frames[i].Source.Path)
+		}
+	}
+	return frames, nil
 }
 
 func (g *GDBDebugger) GetScopes(frameId int) ([]dap.Scope, error) {
@@ -315,12 +1020,90 @@ func (g *GDBDebugger) GetVariables(reference int) ([]dap.Variable, error) {
 	return variables, nil
 }
 
+// hoverExpressionRegexp 悬停求值只允许没有副作用的表达式，比如变量、成员访问、数组下标
+var hoverExpressionRegexp = regexp.MustCompile(`^[\w\s.\[\]*&>:-]+$`)
+
+// Evaluate 在指定栈帧中计算表达式的值，返回的变量需要调用方根据类型设置引用
+// context为hover时，只计算不会修改程序状态的表达式
+func (g *GDBDebugger) Evaluate(expression string, frameId int, context string) (*dap.Variable, error) {
+	if !g.StatusManager.Is(Stopped) {
+		return nil, errors.New("程序未暂停无法计算表达式")
+	}
+	if context == "hover" && (!hoverExpressionRegexp.MatchString(expression) || strings.Contains(expression, "--")) {
+		return nil, fmt.Errorf("expression %s is not supported on hover", expression)
+	}
+	if _, err := g.sendWithTimeOut(OptionTimeout, "stack-select-frame", strconv.Itoa(frameId)); err != nil {
+		return nil, err
+	}
+	targetVar := "evaluateVar"
+	m, err := g.sendWithTimeOut(OptionTimeout, "var-create", targetVar, "*", expression)
+	if err != nil {
+		return nil, err
+	}
+	variable, ok := g.GdbOutputUtil.ParseVarCreate(m)
+	if !ok {
+		return nil, errors.New(g.GdbOutputUtil.GetErrorMessage(m))
+	}
+	if err = g.DeleteVar(targetVar); err != nil {
+		log.Printf("DeleteVar failed: %v\n", err)
+	}
+	variable.Name = expression
+	return variable, nil
+}
+
+// SetExpression 在栈帧frameId中修改表达式的值，返回修改以后的变量
+// 返回的变量需要调用方根据类型设置引用
+func (g *GDBDebugger) SetExpression(expression string, value string, frameId int) (*dap.Variable, error) {
+	if !g.StatusManager.Is(Stopped) {
+		return nil, errors.New("程序未暂停无法修改变量")
+	}
+	if _, err := g.sendWithTimeOut(OptionTimeout, "stack-select-frame", strconv.Itoa(frameId)); err != nil {
+		return nil, err
+	}
+	targetVar := "assignVar"
+	m, err := g.sendWithTimeOut(OptionTimeout, "var-create", targetVar, "*", expression)
+	if err != nil {
+		return nil, err
+	}
+	variable, ok := g.GdbOutputUtil.ParseVarCreate(m)
+	if !ok {
+		return nil, errors.New(g.GdbOutputUtil.GetErrorMessage(m))
+	}
+	defer func() {
+		if err := g.DeleteVar(targetVar); err != nil {
+			log.Printf("DeleteVar failed: %v\n", err)
+		}
+	}()
+	m, err = g.sendWithTimeOut(OptionTimeout, "var-assign", targetVar, value)
+	if err != nil {
+		return nil, err
+	}
+	payload, ok := g.GdbOutputUtil.GetPayloadFromMap(m)
+	if !ok {
+		return nil, errors.New(g.GdbOutputUtil.GetErrorMessage(m))
+	}
+	variable.Name = expression
+	variable.Value = g.GdbOutputUtil.GetStringFromMap(payload, "value")
+	return variable, nil
+}
+
+// getFunctionInfos 等待语法解析完成并返回解析出来的函数列表，超时或者解析失败时返回nil
+func (g *GDBDebugger) getFunctionInfos() []FunctionInfo {
+	select {
+	case <-g.functionInfosParsed:
+		return g.functionInfos
+	case <-time.After(parseSourceTimeout):
+		logrus.Errorf("getFunctionInfos timeout")
+		return nil
+	}
+}
+
 // GetLocalScopeVariables 获取局部变量列表
 func (g *GDBDebugger) GetLocalScopeVariables(reference int) ([]dap.Variable, error) {
 	var variables []dap.Variable
 	var err error
-	if g.functionInfos != nil {
-		variables, err = g.getLocalVariables2(reference)
+	if functionInfos := g.getFunctionInfos(); functionInfos != nil {
+		variables, err = g.getLocalVariables2(reference, functionInfos)
 	} else {
 		variables, err = g.getLocalVariables(reference)
 	}
@@ -357,9 +1140,9 @@ func (g *GDBDebugger) getLocalVariables(reference int) ([]dap.Variable, error) {
 }
 
 // getLocalVariables2 通过静态代码分析获取
-func (g *GDBDebugger) getLocalVariables2(reference int) ([]dap.Variable, error) {
+func (g *GDBDebugger) getLocalVariables2(reference int, functionInfos []FunctionInfo) ([]dap.Variable, error) {
 	frameId := g.ReferenceUtil.GetFrameIDByLocalReference(reference)
-	stackTrace, err := g.GetStackTrace()
+	stackTrace, err := g.GetStackTrace(0)
 	if err != nil {
 		return g.getLocalVariables(reference)
 	}
@@ -373,7 +1156,7 @@ func (g *GDBDebugger) getLocalVariables2(reference int) ([]dap.Variable, error)
 	}
 	// 获取当前方法
 	var target *FunctionInfo
-	for _, f := range g.functionInfos {
+	for _, f := range functionInfos {
 		if f.Name == targetFrame.Name {
 			target = &f
 			break
@@ -490,6 +1273,123 @@ func (g *GDBDebugger) varListChildren(structName string) ([]dap.Variable, error)
 	return variables, nil
 }
 
+// Disassemble 反汇编memoryReference+offset地址附近的指令
+// 从该地址对应的指令开始偏移instructionOffset条指令，返回instructionCount条指令，超出范围的部分使用无效指令填充
+func (g *GDBDebugger) Disassemble(memoryReference string, offset int, instructionOffset int, instructionCount int) ([]dap.DisassembledInstruction, error) {
+	if !g.StatusManager.Is(Stopped) {
+		return nil, errors.New("程序未暂停无法反汇编")
+	}
+	base, err := strconv.ParseUint(memoryReference, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid memory reference %s", memoryReference)
+	}
+	address := uint64(int64(base) + int64(offset))
+	// 优先反汇编地址所在的整个函数，这样可以向前偏移指令
+	m, err := g.sendWithTimeOut(OptionTimeout, "data-disassemble", "-a", fmt.Sprintf("0x%x", address), "--", "5")
+	if err != nil {
+		return nil, err
+	}
+	if g.GdbOutputUtil.GetErrorMessage(m) != "" {
+		start, end := disassembleRange(address, instructionOffset, instructionCount)
+		m, err = g.sendWithTimeOut(OptionTimeout, "data-disassemble",
+			"-s", fmt.Sprintf("0x%x", start), "-e", fmt.Sprintf("0x%x", end), "--", "5")
+		if err != nil {
+			return nil, err
+		}
+		if msg := g.GdbOutputUtil.GetErrorMessage(m); msg != "" {
+			return nil, errors.New(msg)
+		}
+	}
+	instructions := g.GdbOutputUtil.ParseDisassembleOutput(m)
+	for i := range instructions {
+		if instructions[i].Location != nil {
+			instructions[i].Location = g.sourceMap.clientSource(instructions[i].Location.Path)
+		}
+	}
+	return sliceInstructions(instructions, address, instructionOffset, instructionCount), nil
+}
+
+// maxReadMemoryBytes 一次最多读取的内存字节数
+const maxReadMemoryBytes = 1 << 20
+
+// ReadMemory 读取memoryReference+offset开始的count个字节
+// 返回实际读取的起始地址、读取到的内容以及无法读取的字节数
+// count超过maxReadMemoryBytes时只读取前面的部分，客户端从返回的地址加上内容长度处继续读取
+func (g *GDBDebugger) ReadMemory(memoryReference string, offset int, count int) (*dap.ReadMemoryResponseBody, error) {
+	if !g.StatusManager.Is(Stopped) {
+		return nil, errors.New("程序未暂停无法读取内存")
+	}
+	base, err := strconv.ParseUint(memoryReference, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid memory reference %s", memoryReference)
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("invalid count %d", count)
+	}
+	start := uint64(int64(base) + int64(offset))
+	answer := &dap.ReadMemoryResponseBody{Address: fmt.Sprintf("0x%x", start)}
+	if count == 0 {
+		return answer, nil
+	}
+	count = min(count, maxReadMemoryBytes)
+	m, err := g.sendWithTimeOut(OptionTimeout, "data-read-memory-bytes", answer.Address, strconv.Itoa(count))
+	if err != nil {
+		return nil, err
+	}
+	address, data, success := g.GdbOutputUtil.ParseReadMemoryOutput(m)
+	if !success {
+		// 整块内存都不可读
+		answer.UnreadableBytes = count
+		return answer, nil
+	}
+	begin, _ := strconv.ParseUint(address, 0, 64)
+	answer.Address = address
+	answer.Data = base64.StdEncoding.EncodeToString(data)
+	answer.UnreadableBytes = max(int(int64(start)+int64(count)-int64(begin))-len(data), 0)
+	return answer, nil
+}
+
+// WriteMemory 把data写入memoryReference+offset开始的内存，返回写入的字节数
+func (g *GDBDebugger) WriteMemory(memoryReference string, offset int, data []byte) (int, error) {
+	if !g.StatusManager.Is(Stopped) {
+		return 0, errors.New("程序未暂停无法修改内存")
+	}
+	base, err := strconv.ParseUint(memoryReference, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory reference %s", memoryReference)
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	address := fmt.Sprintf("0x%x", uint64(int64(base)+int64(offset)))
+	m, err := g.sendWithTimeOut(OptionTimeout, "data-write-memory-bytes", address, hex.EncodeToString(data))
+	if err != nil {
+		return 0, err
+	}
+	if msg := g.GdbOutputUtil.GetErrorMessage(m); msg != "" {
+		return 0, errors.New(msg)
+	}
+	return len(data), nil
+}
+
+// GetThreads 获取线程列表
+// 程序未暂停时gdb无法获取线程信息，返回默认的主线程
+func (g *GDBDebugger) GetThreads() ([]dap.Thread, error) {
+	if !g.StatusManager.Is(Stopped) {
+		return []dap.Thread{{Id: 1, Name: "main"}}, nil
+	}
+	m, err := g.sendWithTimeOut(OptionTimeout, "thread-info")
+	if err != nil {
+		log.Printf("GetThreads fail, err = %s\n", err)
+		return nil, err
+	}
+	threads, success := g.GdbOutputUtil.ParseThreadsOutput(m)
+	if !success {
+		return nil, errors.New(g.GdbOutputUtil.GetErrorMessage(m))
+	}
+	return threads, nil
+}
+
 // getCurrentThreadId 获取当前线程id
 func (g *GDBDebugger) getCurrentThreadId() (string, error) {
 	// 获取当前线程id
@@ -510,13 +1410,27 @@ func (g *GDBDebugger) getCurrentThreadId() (string, error) {
 func (g *GDBDebugger) gdbNotificationCallback(m map[string]interface{}) {
 	typ := g.GdbOutputUtil.GetStringFromMap(m, "type")
 	switch typ {
+	case "console":
+		g.processConsoleOutput(g.GdbOutputUtil.GetStringFromMap(m, "payload"))
+	case "notify":
+		// 断点被修改，比如pending断点的位置被加载
+		switch g.GdbOutputUtil.GetStringFromMap(m, "class") {
+		case "breakpoint-modified":
+			g.processBreakpointModified(g.GdbOutputUtil.GetInterfaceFromMap(m, "payload"))
+		case "thread-group-started":
+			// 用户程序启动，记录进程id
+			payload := g.GdbOutputUtil.GetInterfaceFromMap(m, "payload")
+			pid, _ := strconv.Atoi(g.GdbOutputUtil.GetStringFromMap(payload, "pid"))
+			atomic.StoreInt32(&g.inferiorPid, int32(pid))
+		}
 	case "exec":
 		class := g.GdbOutputUtil.GetStringFromMap(m, "class")
 		switch class {
 		case "stopped":
-			// 处理程序停止的事件
-			g.processStoppedData(g.GdbOutputUtil.GetInterfaceFromMap(m, "payload"))
+			// 先设置状态再处理，processStoppedData中静默继续执行时，
+			// 随后的running通知才能把状态改回Running，客户端收到stopped事件以后也可以立即获取栈帧
 			g.StatusManager.Set(Stopped)
+			g.processStoppedData(g.GdbOutputUtil.GetInterfaceFromMap(m, "payload"))
 		case "running":
 			g.processRunningData()
 			g.StatusManager.Set(Running)
@@ -531,24 +1445,179 @@ func (g *GDBDebugger) processStoppedData(m interface{}) {
 	if stoppedOutput == nil {
 		return
 	}
+	// 执行记录遇到不支持的指令，或者程序即将退出时，gdb会停止程序并且没有停止原因
+	// 关闭执行记录以后继续执行，保证程序可以正常运行结束，其他没有原因的停止照常处理
+	if stoppedOutput.reason == constants.Unknown && g.recordStopMessage != "" {
+		reason := g.recordStopMessage
+		g.recordStopMessage = ""
+		g.stopRecord(reason)
+		g.continueSilently()
+		return
+	}
+	// 程序收到信号，没有开启对应的异常断点时，把信号交给程序处理
+	if stoppedOutput.reason == constants.ExceptionStopped {
+		g.exceptionLock.Lock()
+		stop := shouldStopOnSignal(g.exceptionFilters, stoppedOutput.signalName)
+		if stop {
+			g.lastException = stoppedOutput
+		}
+		g.exceptionLock.Unlock()
+		if !stop {
+			g.continueSilently()
+			return
+		}
+	}
+	// 客户端要求反向调试时，程序第一次停止时开启执行记录
+	if g.startOption.Record && !stoppedOutput.exited() {
+		g.startRecord()
+	}
+	if stoppedOutput.reason == constants.BreakpointStopped ||
+		(stoppedOutput.reason == constants.DataBreakpointStopped && !stoppedOutput.outOfScope) {
+		info, hit := g.checkBreakpointHit(stoppedOutput.breakpointNumber)
+		// 命中次数条件不满足的断点，直接继续执行
+		if !hit {
+			g.continueSilently()
+			return
+		}
+		// 日志断点输出日志以后继续执行，求值需要等待gdb响应，不能在gdb输出协程中进行
+		// 客户端认为程序仍在运行，求值期间拒绝用户的执行请求，避免和静默继续执行交错
+		if info != nil && info.logMessage != "" {
+			g.StatusManager.Set(Running)
+			gosync.Go(context.Background(), func(ctx context.Context) {
+				g.sendLogMessage(info.logMessage, stoppedOutput)
+				g.continueSilently()
+			})
+			return
+		}
+	}
 	// 停留在断点
-	if stoppedOutput.reason == constants.StepStopped || stoppedOutput.reason == constants.BreakpointStopped {
+	if stoppedOutput.reason == constants.StepStopped || stoppedOutput.reason == constants.BreakpointStopped ||
+		stoppedOutput.reason == constants.DataBreakpointStopped || stoppedOutput.reason == constants.PauseStopped ||
+		stoppedOutput.reason == constants.NoHistoryStopped || stoppedOutput.reason == constants.ExceptionStopped {
+		body := dap.StoppedEventBody{
+			Reason:            string(stoppedOutput.reason),
+			Description:       stoppedOutput.description(),
+			ThreadId:          stoppedOutput.threadId,
+			AllThreadsStopped: stoppedOutput.allThreadsStopped,
+		}
+		if number, err := strconv.Atoi(stoppedOutput.breakpointNumber); err == nil {
+			body.HitBreakpointIds = []int{number}
+		}
+		if stoppedOutput.reason == constants.ExceptionStopped {
+			body.Text = stoppedOutput.signalMeaning
+		}
+		// dap没有执行记录结束的停止原因，作为单步停止处理
+		if stoppedOutput.reason == constants.NoHistoryStopped {
+			body.Reason = string(constants.StepStopped)
+		}
 		// 返回停留的断点位置
 		g.callback(&dap.StoppedEvent{
 			Event: *NewEvent(0, "stopped"),
-			Body:  dap.StoppedEventBody{Reason: string(stoppedOutput.reason)},
+			Body:  body,
 		})
 	}
-	if stoppedOutput.reason == constants.ExitedNormally {
-		// 程序退出
+	if stoppedOutput.exited() {
+		// 程序退出，先读完并发送剩余的程序输出和退出码，再结束调试会话
+		g.drainUserOutput()
+		g.outputForwarder.flush(true)
+		// 标准的exited事件中没有描述信息，同时在控制台输出退出信息
+		g.callback(&dap.OutputEvent{
+			Event: *NewEvent(0, "output"),
+			Body:  dap.OutputEventBody{Category: "console", Output: stoppedOutput.exitMessage() + "\n"},
+		})
+		g.callback(NewExitedEventMessage(NewExitedEvent(stoppedOutput.exitCode, stoppedOutput.exitMessage())))
 		g.callback(&dap.TerminatedEvent{
 			Event: *NewEvent(0, "terminated"),
 		})
 	}
 }
 
+// processBreakpointModified 处理断点被修改的通知，断点生效状态或者位置发生变化时通知用户
+// 断点每次命中时gdb也会发送该通知，这种情况不需要通知用户
+func (g *GDBDebugger) processBreakpointModified(payload interface{}) {
+	output, ok := g.GdbOutputUtil.ParseBkpt(payload)
+	if !ok {
+		return
+	}
+	g.breakpointInfoLock.Lock()
+	info, ok := g.breakpointInfos[output.Number]
+	if !ok || (info.verified == !output.Pending && (output.Line == 0 || info.line == output.Line)) {
+		g.breakpointInfoLock.Unlock()
+		return
+	}
+	info.verified = !output.Pending
+	if output.Line != 0 {
+		info.line = output.Line
+	}
+	breakpoint := info.toBreakpoint(g.sourceMap.clientSource(info.source))
+	g.breakpointInfoLock.Unlock()
+	g.callback(&dap.BreakpointEvent{
+		Event: *NewEvent(0, string(constants.BreakpointEvent)),
+		Body: dap.BreakpointEventBody{
+			// DAP协议中断点改变的原因为changed，和旧协议的ChangeType不同
+			Reason:     "changed",
+			Breakpoint: breakpoint,
+		},
+	})
+}
+
+// checkBreakpointHit 记录断点的命中次数，并判断是否满足命中次数条件
+func (g *GDBDebugger) checkBreakpointHit(number string) (*breakpointInfo, bool) {
+	g.breakpointInfoLock.Lock()
+	defer g.breakpointInfoLock.Unlock()
+	info, ok := g.breakpointInfos[number]
+	if !ok {
+		return nil, true
+	}
+	info.hits++
+	if info.hitCondition == nil {
+		return info, true
+	}
+	return info, info.hitCondition.satisfied(info.hits)
+}
+
+// sendLogMessage 计算日志断点的消息，并以console类型的OutputEvent发送给用户
+func (g *GDBDebugger) sendLogMessage(logMessage string, stoppedOutput *StoppedOutput) {
+	output := interpolateLogMessage(logMessage, func(expression string) string {
+		m, err := g.GDB.SendWithTimeout(OptionTimeout, "data-evaluate-expression", expression)
+		if err != nil {
+			return fmt.Sprintf("<error: %s>", err)
+		}
+		if msg := g.GdbOutputUtil.GetErrorMessage(m); msg != "" {
+			return fmt.Sprintf("<error: %s>", msg)
+		}
+		payload := g.GdbOutputUtil.GetInterfaceFromMap(m, "payload")
+		return g.GdbOutputUtil.GetStringFromMap(payload, "value")
+	})
+	g.callback(&dap.OutputEvent{
+		Event: *NewEvent(0, "output"),
+		Body: dap.OutputEventBody{
+			Category: "console",
+			Output:   output + "\n",
+			Source:   g.sourceMap.clientSource(stoppedOutput.file),
+			Line:     stoppedOutput.line,
+		},
+	})
+}
+
+// continueSilently 程序停止但是不需要通知用户时，继续执行程序，并跳过产生的continued事件
+// 可能在gdb的输出协程中调用，所以只能使用异步命令
+func (g *GDBDebugger) continueSilently() {
+	// 客户端没有收到stopped事件，在running通知到达之前也不能接受用户的执行请求
+	g.StatusManager.Set(Running)
+	atomic.AddInt64(&g.skipContinuedEventCount, 1)
+	if err := g.GDB.SendAsync(func(obj map[string]interface{}) {}, "exec-continue"); err != nil {
+		atomic.AddInt64(&g.skipContinuedEventCount, -1)
+		g.StatusManager.Set(Stopped)
+		logrus.Errorf("continueSilently fail, err = %s", err)
+	}
+}
+
 // processRunningData 处理gdb返回的running事件
 func (g *GDBDebugger) processRunningData() {
+	g.exceptionLock.Lock()
+	g.lastException = nil
+	g.exceptionLock.Unlock()
 	// 程序执行，如果有需要跳过的continue事件，则跳过
 	skipCount := atomic.LoadInt64(&g.skipContinuedEventCount)
 	if skipCount > 0 {
@@ -0,0 +1,31 @@
+package gdb_debugger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleInput(t *testing.T) {
+	// 输入原样写入，不会按空白分割
+	assert.Equal(t, []byte("1 2  3\n"), consoleInput("1 2  3\n", false))
+	// 以换行结尾时只需要一个Ctrl-D
+	assert.Equal(t, []byte("10\n\x04"), consoleInput("10\n", true))
+	// 不完整的行需要先提交，再发送EOF
+	assert.Equal(t, []byte("10\x04\x04"), consoleInput("10", true))
+	assert.Equal(t, []byte("\x04"), consoleInput("", true))
+}
+
+func TestGetFunctionInfos(t *testing.T) {
+	// 设置函数断点时语法解析可能还没有完成，需要等待解析结果
+	g := &GDBDebugger{functionInfosParsed: make(chan struct{})}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		g.functionInfos = []FunctionInfo{{Name: "main"}}
+		close(g.functionInfosParsed)
+	}()
+	functionInfos := g.getFunctionInfos()
+	assert.Equal(t, 1, len(functionInfos))
+	assert.Equal(t, "main", functionInfos[0].Name)
+}
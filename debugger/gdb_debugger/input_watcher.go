@@ -0,0 +1,90 @@
+package gdb_debugger
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// inputPollInterval 检查用户程序是否在等待输入的间隔
+const inputPollInterval = 100 * time.Millisecond
+
+// inputWatcher 通过/proc观察用户程序是否阻塞在读取终端上，
+// 状态变化时通知客户端，客户端可以据此聚焦输入框
+type inputWatcher struct {
+	// tty 用户程序终端的路径
+	tty     string
+	send    func(waiting bool)
+	waiting bool
+}
+
+func newInputWatcher(tty string, send func(waiting bool)) *inputWatcher {
+	return &inputWatcher{
+		tty:  tty,
+		send: send,
+	}
+}
+
+// check 检查进程pid的状态，和上一次不同时通知客户端
+// 进程已经不存在时返回错误
+func (w *inputWatcher) check(pid int) error {
+	waiting, err := waitingForInput(pid, w.tty)
+	if err != nil {
+		return err
+	}
+	w.update(waiting)
+	return nil
+}
+
+// update 更新等待输入的状态，只在状态变化时通知
+func (w *inputWatcher) update(waiting bool) {
+	if waiting == w.waiting {
+		return
+	}
+	w.waiting = waiting
+	w.send(waiting)
+}
+
+// waitingForInput 判断进程pid是否有线程阻塞在读取终端tty的read系统调用上
+func waitingForInput(pid int, tty string) (bool, error) {
+	tasks, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return false, err
+	}
+	for _, task := range tasks {
+		content, err := os.ReadFile(fmt.Sprintf("/proc/%d/task/%s/syscall", pid, task.Name()))
+		if err != nil {
+			continue
+		}
+		fd, ok := parseReadSyscall(string(content))
+		if !ok {
+			continue
+		}
+		link, err := os.Readlink(fmt.Sprintf("/proc/%d/fd/%d", pid, fd))
+		if err == nil && link == tty {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseReadSyscall 解析/proc/<pid>/syscall，线程正在执行read系统调用时返回读取的文件描述符
+// 文件内容为系统调用号以及十六进制的参数，线程不在系统调用中时为running
+func parseReadSyscall(content string) (int, bool) {
+	fields := strings.Fields(content)
+	if len(fields) < 2 {
+		return 0, false
+	}
+	number, err := strconv.Atoi(fields[0])
+	if err != nil || number != syscall.SYS_READ {
+		return 0, false
+	}
+	fd, err := strconv.ParseInt(fields[1], 0, 64)
+	if err != nil {
+		return 0, false
+	}
+	return int(fd), true
+}
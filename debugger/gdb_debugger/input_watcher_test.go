@@ -0,0 +1,54 @@
+package gdb_debugger
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReadSyscall(t *testing.T) {
+	fd, ok := parseReadSyscall(fmt.Sprintf("%d 0x3 0x7ffd4a1c 0x400 0x0 0x0 0x0 0x7ffd4a00 0x7f12\n", syscall.SYS_READ))
+	assert.True(t, ok)
+	assert.Equal(t, 3, fd)
+	_, ok = parseReadSyscall(fmt.Sprintf("%d 0x1 0x7ffd4a1c 0x4 0x0 0x0 0x0 0x7ffd4a00 0x7f12\n", syscall.SYS_READ+1))
+	assert.False(t, ok)
+	_, ok = parseReadSyscall("running\n")
+	assert.False(t, ok)
+	_, ok = parseReadSyscall("-1 0x7ffd4a00 0x7f12\n")
+	assert.False(t, ok)
+}
+
+func TestInputWatcher(t *testing.T) {
+	if _, err := os.Stat("/proc/self/syscall"); err != nil {
+		t.Skip("/proc/<pid>/syscall is not available")
+	}
+	ptm, pts, err := pty.Open()
+	assert.Nil(t, err)
+	defer ptm.Close()
+	defer pts.Close()
+	// cat阻塞在读取终端上
+	cmd := exec.Command("cat")
+	cmd.Stdin = pts
+	assert.Nil(t, cmd.Start())
+	defer cmd.Process.Kill()
+
+	var states []bool
+	watcher := newInputWatcher(pts.Name(), func(waiting bool) {
+		states = append(states, waiting)
+	})
+	assert.Eventually(t, func() bool {
+		return watcher.check(cmd.Process.Pid) == nil && watcher.waiting
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []bool{true}, states)
+
+	// 程序退出以后返回错误
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+	assert.NotNil(t, watcher.check(cmd.Process.Pid))
+}
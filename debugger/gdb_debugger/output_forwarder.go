@@ -0,0 +1,107 @@
+package gdb_debugger
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// maxUserOutputBytes 一次调试中转发给用户的程序输出上限，超出以后丢弃
+	maxUserOutputBytes = 1 << 20
+	// maxOutputChunkBytes 每次发送的程序输出上限，未发送的部分留到下一次发送
+	maxOutputChunkBytes = 16 << 10
+)
+
+// outputForwarder 把用户程序的输出转发给客户端
+// 输出先写入缓冲区，由定时器按块发送，避免死循环打印时大量的事件挤占连接
+// 发送时保证不会把一个UTF-8字符拆到两个事件中
+type outputForwarder struct {
+	send func(output string)
+	// sendLock 在取出输出到发送完成期间持有，保证并发flush时输出按顺序发送
+	sendLock sync.Mutex
+
+	lock      sync.Mutex
+	buffer    []byte
+	total     int
+	limit     int
+	chunk     int
+	truncated bool
+	notified  bool
+}
+
+func newOutputForwarder(send func(output string), limit int, chunk int) *outputForwarder {
+	return &outputForwarder{
+		send:  send,
+		limit: limit,
+		chunk: chunk,
+	}
+}
+
+// write 写入程序的输出，超出上限的部分会被丢弃
+func (f *outputForwarder) write(p []byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.truncated {
+		return
+	}
+	if f.total+len(p) > f.limit {
+		p = p[:f.limit-f.total]
+		f.truncated = true
+	}
+	f.total += len(p)
+	f.buffer = append(f.buffer, p...)
+	if f.truncated {
+		// 截断的位置可能在字符中间，丢弃不完整的字符
+		f.buffer = f.buffer[:utf8PrefixLength(f.buffer, len(f.buffer))]
+	}
+}
+
+// flush 发送缓冲区中的输出，每次最多发送chunk个字节
+// final为true时发送所有的输出，包括末尾不完整的UTF-8字符
+func (f *outputForwarder) flush(final bool) {
+	f.sendLock.Lock()
+	defer f.sendLock.Unlock()
+	f.lock.Lock()
+	var outputs []string
+	for len(f.buffer) != 0 {
+		n := utf8PrefixLength(f.buffer, f.chunk)
+		if n == 0 {
+			if !final {
+				break
+			}
+			n = len(f.buffer)
+		}
+		outputs = append(outputs, string(f.buffer[:n]))
+		f.buffer = f.buffer[n:]
+		if !final {
+			break
+		}
+	}
+	// 输出全部发送以后，提示用户输出被截断，只提示一次
+	if f.truncated && !f.notified && len(f.buffer) == 0 {
+		outputs = append(outputs, fmt.Sprintf("\n[output truncated: exceeded %d bytes]\n", f.limit))
+		f.notified = true
+	}
+	f.lock.Unlock()
+	for _, output := range outputs {
+		f.send(output)
+	}
+}
+
+// utf8PrefixLength 返回b中不超过n个字节，且不以不完整UTF-8字符结尾的前缀长度
+func utf8PrefixLength(b []byte, n int) int {
+	if n > len(b) {
+		n = len(b)
+	}
+	// UTF-8字符最多4个字节，只需要检查末尾的几个字节
+	for i := n - 1; i >= 0 && i > n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:n]) {
+				return i
+			}
+			break
+		}
+	}
+	return n
+}
@@ -0,0 +1,89 @@
+package gdb_debugger
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputForwarderUTF8Boundary(t *testing.T) {
+	var outputs []string
+	forwarder := newOutputForwarder(func(output string) {
+		outputs = append(outputs, output)
+	}, 1024, 4)
+
+	// "你" 占3个字节，第二个字符被拆到了两次读取中
+	data := []byte("a你好")
+	forwarder.write(data[:5])
+	forwarder.flush(false)
+	assert.Equal(t, []string{"a你"}, outputs)
+	forwarder.flush(false)
+	assert.Equal(t, []string{"a你"}, outputs)
+
+	forwarder.write(data[5:])
+	forwarder.flush(false)
+	assert.Equal(t, []string{"a你", "好"}, outputs)
+}
+
+func TestOutputForwarderThrottle(t *testing.T) {
+	var outputs []string
+	forwarder := newOutputForwarder(func(output string) {
+		outputs = append(outputs, output)
+	}, 1024, 4)
+
+	forwarder.write([]byte("0123456789"))
+	forwarder.flush(false)
+	assert.Equal(t, []string{"0123"}, outputs)
+	forwarder.flush(true)
+	assert.Equal(t, []string{"0123", "4567", "89"}, outputs)
+}
+
+func TestOutputForwarderLimit(t *testing.T) {
+	var outputs []string
+	forwarder := newOutputForwarder(func(output string) {
+		outputs = append(outputs, output)
+	}, 8, 1024)
+
+	forwarder.write([]byte("12345"))
+	forwarder.write([]byte("6789"))
+	forwarder.write([]byte("more"))
+	forwarder.flush(false)
+	assert.Equal(t, "12345678", outputs[0])
+	assert.True(t, strings.Contains(outputs[1], "output truncated"))
+
+	// 截断提示只发送一次
+	forwarder.flush(true)
+	assert.Equal(t, 2, len(outputs))
+}
+
+func TestOutputForwarderOrder(t *testing.T) {
+	var lock sync.Mutex
+	var outputs []string
+	forwarder := newOutputForwarder(func(output string) {
+		// 让出执行权，让其他flush有机会插到前面
+		runtime.Gosched()
+		lock.Lock()
+		outputs = append(outputs, output)
+		lock.Unlock()
+	}, 1024, 1)
+
+	// 定时发送和程序退出时的发送可能同时进行，输出的顺序不能改变
+	data := strings.Repeat("0123456789", 50)
+	forwarder.write([]byte(data))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				forwarder.flush(false)
+			}
+		}()
+	}
+	wg.Wait()
+	forwarder.flush(true)
+	assert.Equal(t, data, strings.Join(outputs, ""))
+}
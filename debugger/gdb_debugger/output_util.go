@@ -1,6 +1,7 @@
 package gdb_debugger
 
 import (
+	"encoding/hex"
 	"fmt"
 	"path/filepath"
 	"regexp"
@@ -63,6 +64,69 @@ func (g *GDBOutputUtil) ParseAddBreakpointOutput(m map[string]interface{}) (bool
 	return true, number
 }
 
+// BreakpointOutput 断点插入以后gdb返回的断点信息
+type BreakpointOutput struct {
+	Number string
+	File   string
+	Line   int
+	// Pending 断点的位置还没有加载，比如位于还没有加载的动态库中，加载以后gdb会通知断点被修改
+	Pending bool
+}
+
+// ParseBreakpointOutput 解析break-insert返回的断点信息，包括断点实际所在的文件和行号
+// 断点有多个位置时(比如模板函数)，使用第一个位置
+// class->done
+//
+//	payload->{
+//		bkpt—>{
+//			  number -> 1
+//			  func -> main
+//			  fullname -> /var/fanCode/tempDir/56370c2d-6d34-11ef-9e80-5a7990d94760/main.c
+//			  line -> 43
+//			  addr -> 0x0000000000000806
+//			  locations -> [{number -> 1.1, fullname -> ..., line -> 43}]
+//			}
+func (g *GDBOutputUtil) ParseBreakpointOutput(m map[string]interface{}) (*BreakpointOutput, bool) {
+	payload, success := g.GetPayloadFromMap(m)
+	if !success {
+		return nil, false
+	}
+	return g.ParseBkpt(payload)
+}
+
+// ParseBkpt 解析payload中的bkpt，break-insert的响应以及breakpoint-modified通知都使用该结构
+// 断点处于pending状态时，bkpt中只有pending字段记录原始位置
+func (g *GDBOutputUtil) ParseBkpt(payload interface{}) (*BreakpointOutput, bool) {
+	bkpt, ok := g.GetInterfaceFromMap(payload, "bkpt").(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	answer := &BreakpointOutput{
+		Number:  g.GetStringFromMap(bkpt, "number"),
+		File:    g.GetStringFromMap(bkpt, "fullname"),
+		Line:    g.GetIntFromMap(bkpt, "line"),
+		Pending: g.CheckKeyFromMap(bkpt, "pending"),
+	}
+	if answer.File == "" {
+		if locations := g.GetListFromMap(bkpt, "locations"); len(locations) != 0 {
+			answer.File = g.GetStringFromMap(locations[0], "fullname")
+			answer.Line = g.GetIntFromMap(locations[0], "line")
+		}
+	}
+	return answer, true
+}
+
+// GetErrorMessage 读取gdb错误响应中的错误信息
+// class -> error
+// payload -> {msg -> No symbol "x" in current context.}
+func (g *GDBOutputUtil) GetErrorMessage(m map[string]interface{}) string {
+	if class := g.GetStringFromMap(m, "class"); class != "error" {
+		return ""
+	}
+	payload := g.GetInterfaceFromMap(m, "payload")
+	return g.GetStringFromMap(payload, "msg")
+}
+
 // ParseRemoveBreakpointOutput 解析移除断点输出
 // class -> done
 func (g *GDBOutputUtil) ParseRemoveBreakpointOutput(m map[string]interface{}) bool {
@@ -110,6 +174,7 @@ func (g *GDBOutputUtil) ParseStackTraceOutput(m map[string]interface{}) []dap.St
 				Name: filepath.Base(fullname),
 				Path: fullname,
 			},
+			InstructionPointerReference: g.GetStringFromMap(frame, "addr"),
 		}
 		answer = append(answer, stack)
 	}
@@ -264,6 +329,15 @@ func (g *GDBOutputUtil) ParseVariablesOutput(m map[string]interface{}) []dap.Var
 // stopped-threads->all
 // core->4
 func (g *GDBOutputUtil) ParseStoppedEventOutput(m interface{}) *StoppedOutput {
+	answer := g.parseStoppedReason(m)
+	answer.threadId = g.GetIntFromMap(m, "thread-id")
+	// stopped-threads为all，或者是停止的线程id列表
+	answer.allThreadsStopped = g.GetStringFromMap(m, "stopped-threads") == "all"
+	return answer
+}
+
+// parseStoppedReason 根据停止原因解析stopped事件
+func (g *GDBOutputUtil) parseStoppedReason(m interface{}) *StoppedOutput {
 	r := g.GetStringFromMap(m, "reason")
 	if r == "breakpoint-hit" {
 		frame := g.GetInterfaceFromMap(m, "frame")
@@ -271,9 +345,21 @@ func (g *GDBOutputUtil) ParseStoppedEventOutput(m interface{}) *StoppedOutput {
 		lineStr := g.GetStringFromMap(frame, "line")
 		line, _ := strconv.Atoi(lineStr)
 		return &StoppedOutput{
-			reason: constants.BreakpointStopped,
-			file:   fullname,
-			line:   line,
+			reason:           constants.BreakpointStopped,
+			file:             fullname,
+			line:             line,
+			breakpointNumber: g.GetStringFromMap(m, "bkptno"),
+		}
+	} else if r == "watchpoint-trigger" || r == "read-watchpoint-trigger" || r == "access-watchpoint-trigger" {
+		return g.parseWatchpointStoppedOutput(m)
+	} else if r == "watchpoint-scope" {
+		frame := g.GetInterfaceFromMap(m, "frame")
+		return &StoppedOutput{
+			reason:           constants.DataBreakpointStopped,
+			file:             g.GetStringFromMap(frame, "fullname"),
+			line:             g.GetIntFromMap(frame, "line"),
+			breakpointNumber: g.GetStringFromMap(m, "wpnum"),
+			outOfScope:       true,
 		}
 	} else if r == "end-stepping-range" || r == "function-finished" {
 		frame := g.GetInterfaceFromMap(m, "frame")
@@ -285,10 +371,50 @@ func (g *GDBOutputUtil) ParseStoppedEventOutput(m interface{}) *StoppedOutput {
 			file:   fullname,
 			line:   line,
 		}
+	} else if r == "signal-received" && isInterruptSignal(g.GetStringFromMap(m, "signal-name")) {
+		// exec-interrupt暂停程序，gdb会以收到SIGINT信号的方式停止
+		frame := g.GetInterfaceFromMap(m, "frame")
+		return &StoppedOutput{
+			reason: constants.PauseStopped,
+			file:   g.GetStringFromMap(frame, "fullname"),
+			line:   g.GetIntFromMap(frame, "line"),
+		}
+	} else if r == "signal-received" {
+		frame := g.GetInterfaceFromMap(m, "frame")
+		return &StoppedOutput{
+			reason:        constants.ExceptionStopped,
+			file:          g.GetStringFromMap(frame, "fullname"),
+			line:          g.GetIntFromMap(frame, "line"),
+			signalName:    g.GetStringFromMap(m, "signal-name"),
+			signalMeaning: g.GetStringFromMap(m, "signal-meaning"),
+		}
+	} else if r == "no-history" {
+		// 反向执行到达了执行记录的起点，或者正向执行到达了记录的终点
+		frame := g.GetInterfaceFromMap(m, "frame")
+		return &StoppedOutput{
+			reason: constants.NoHistoryStopped,
+			file:   g.GetStringFromMap(frame, "fullname"),
+			line:   g.GetIntFromMap(frame, "line"),
+		}
 	} else if r == "exited-normally" {
 		return &StoppedOutput{
 			reason: constants.ExitedNormally,
 		}
+	} else if r == "exited" {
+		// gdb以八进制输出退出码
+		exitCode, _ := strconv.ParseInt(g.GetStringFromMap(m, "exit-code"), 8, 32)
+		return &StoppedOutput{
+			reason:   constants.Exited,
+			exitCode: int(exitCode),
+		}
+	} else if r == "exited-signalled" {
+		signalName := g.GetStringFromMap(m, "signal-name")
+		return &StoppedOutput{
+			reason:        constants.ExitedSignalled,
+			exitCode:      signalExitCode(signalName),
+			signalName:    signalName,
+			signalMeaning: g.GetStringFromMap(m, "signal-meaning"),
+		}
 	} else {
 		return &StoppedOutput{
 			reason: constants.Unknown,
@@ -296,6 +422,141 @@ func (g *GDBOutputUtil) ParseStoppedEventOutput(m interface{}) *StoppedOutput {
 	}
 }
 
+// isInterruptSignal 判断是否是中断程序产生的信号，non-stop模式下中断的信号名称为0
+func isInterruptSignal(signalName string) bool {
+	return signalName == "SIGINT" || signalName == "0"
+}
+
+// ParseDisassembleOutput 解析data-disassemble的输出，包含源码信息时每条指令都会设置源码位置
+// class->done
+//
+//	payload->{
+//	 asm_insns->[
+//	  {
+//	   src_and_asm_line->{
+//	    line->5
+//	    fullname->/tmp/main.c
+//	    line_asm_insn->[
+//	     {address->0x0000555555555149, func-name->main, offset->0, inst->push   %rbp, opcodes->55}
+//	    ]
+//	   }
+//	  }
+//	 ]
+//	}
+//
+// 没有源码信息时，asm_insns中直接是指令列表
+func (g *GDBOutputUtil) ParseDisassembleOutput(m map[string]interface{}) []dap.DisassembledInstruction {
+	payload, success := g.GetPayloadFromMap(m)
+	if !success {
+		return []dap.DisassembledInstruction{}
+	}
+	answer := make([]dap.DisassembledInstruction, 0, 20)
+	for _, item := range g.GetListFromMap(payload, "asm_insns") {
+		srcLine := g.GetInterfaceFromMap(item, "src_and_asm_line")
+		if srcLine == nil {
+			answer = append(answer, g.parseAsmInstruction(item))
+			continue
+		}
+		fullname := g.GetStringFromMap(srcLine, "fullname")
+		line := g.GetIntFromMap(srcLine, "line")
+		for _, insn := range g.GetListFromMap(srcLine, "line_asm_insn") {
+			instruction := g.parseAsmInstruction(insn)
+			if fullname != "" {
+				instruction.Location = &dap.Source{Name: filepath.Base(fullname), Path: fullname}
+				instruction.Line = line
+			}
+			answer = append(answer, instruction)
+		}
+	}
+	return answer
+}
+
+// parseAsmInstruction 解析单条汇编指令
+func (g *GDBOutputUtil) parseAsmInstruction(m interface{}) dap.DisassembledInstruction {
+	instruction := dap.DisassembledInstruction{
+		Address:          g.GetStringFromMap(m, "address"),
+		InstructionBytes: g.GetStringFromMap(m, "opcodes"),
+		Instruction:      g.GetStringFromMap(m, "inst"),
+	}
+	if fun := g.GetStringFromMap(m, "func-name"); fun != "" {
+		instruction.Symbol = fmt.Sprintf("%s+%s", fun, g.GetStringFromMap(m, "offset"))
+	}
+	return instruction
+}
+
+// ParseReadMemoryOutput 解析data-read-memory-bytes的输出，返回从第一块内存开始连续可读的内容
+// gdb只返回可读的内存块，内存块之间可能存在不可读的空隙
+// class->done
+//
+//	payload->{
+//	 memory->[
+//	  {begin->0x7fffffffe3a0, offset->0x0, end->0x7fffffffe3a4, contents->01000000}
+//	 ]
+//	}
+func (g *GDBOutputUtil) ParseReadMemoryOutput(m map[string]interface{}) (string, []byte, bool) {
+	payload, success := g.GetPayloadFromMap(m)
+	if !success {
+		return "", nil, false
+	}
+	var address string
+	var data []byte
+	var next uint64
+	for i, block := range g.GetListFromMap(payload, "memory") {
+		begin, err := strconv.ParseUint(g.GetStringFromMap(block, "begin"), 0, 64)
+		if err != nil {
+			return "", nil, false
+		}
+		if i == 0 {
+			address = g.GetStringFromMap(block, "begin")
+		} else if begin != next {
+			break
+		}
+		contents, err := hex.DecodeString(g.GetStringFromMap(block, "contents"))
+		if err != nil {
+			return "", nil, false
+		}
+		data = append(data, contents...)
+		next = begin + uint64(len(contents))
+	}
+	return address, data, address != ""
+}
+
+// ParseThreadsOutput 解析thread-info的输出
+// class->done
+//
+//	payload->{
+//	 threads->[
+//	  {
+//	   id->1
+//	   target-id->Thread 0x7ffff7d89740 (LWP 1234)
+//	   name->main
+//	   frame->{...}
+//	   state->stopped
+//	  }
+//	 ]
+//	 current-thread-id->1
+//	}
+func (g *GDBOutputUtil) ParseThreadsOutput(m map[string]interface{}) ([]dap.Thread, bool) {
+	payload, success := g.GetPayloadFromMap(m)
+	if !success {
+		return nil, false
+	}
+	threadList := g.GetListFromMap(payload, "threads")
+	answer := make([]dap.Thread, 0, len(threadList))
+	for _, t := range threadList {
+		id := g.GetIntFromMap(t, "id")
+		name := g.GetStringFromMap(t, "name")
+		targetId := g.GetStringFromMap(t, "target-id")
+		if name == "" {
+			name = targetId
+		} else if targetId != "" {
+			name = fmt.Sprintf("%s (%s)", name, targetId)
+		}
+		answer = append(answer, dap.Thread{Id: id, Name: name})
+	}
+	return answer, true
+}
+
 // ParseVarCreate 解析var-create响应
 // class -> done
 //
@@ -322,10 +583,104 @@ func (g *GDBOutputUtil) ParseVarCreate(m map[string]any) (*dap.Variable, bool) {
 	return variable, true
 }
 
+// parseWatchpointStoppedOutput 解析观察点触发的stopped事件
+// reason->watchpoint-trigger
+// wpt->{number->2, exp->p}
+// value->{old->0x0, new->0x5555555592a0}
+// 读观察点为hw-rwpt，访问观察点为hw-awpt，只读取时value中只有value字段
+func (g *GDBOutputUtil) parseWatchpointStoppedOutput(m interface{}) *StoppedOutput {
+	var wpt interface{}
+	for _, key := range []string{"wpt", "hw-rwpt", "hw-awpt"} {
+		if wpt = g.GetInterfaceFromMap(m, key); wpt != nil {
+			break
+		}
+	}
+	frame := g.GetInterfaceFromMap(m, "frame")
+	value := g.GetInterfaceFromMap(m, "value")
+	answer := &StoppedOutput{
+		reason:           constants.DataBreakpointStopped,
+		file:             g.GetStringFromMap(frame, "fullname"),
+		line:             g.GetIntFromMap(frame, "line"),
+		breakpointNumber: g.GetStringFromMap(wpt, "number"),
+		expression:       g.GetStringFromMap(wpt, "exp"),
+		oldValue:         g.GetStringFromMap(value, "old"),
+		newValue:         g.GetStringFromMap(value, "new"),
+	}
+	if !g.CheckKeyFromMap(value, "new") {
+		answer.newValue = g.GetStringFromMap(value, "value")
+	}
+	return answer
+}
+
+// ParseWatchpointOutput 解析break-watch的输出，返回观察点编号
+// class->done
+// payload->{wpt->{number->2, exp->p}}
+func (g *GDBOutputUtil) ParseWatchpointOutput(m map[string]interface{}) (string, bool) {
+	payload, success := g.GetPayloadFromMap(m)
+	if !success {
+		return "", false
+	}
+	for _, key := range []string{"wpt", "hw-rwpt", "hw-awpt"} {
+		if wpt := g.GetInterfaceFromMap(payload, key); wpt != nil {
+			return g.GetStringFromMap(wpt, "number"), true
+		}
+	}
+	return "", false
+}
+
 type StoppedOutput struct {
 	reason constants.StoppedReasonType
 	file   string
 	line   int
+	// breakpointNumber 命中的断点编号
+	breakpointNumber string
+	// 观察点触发时，观察的表达式以及修改前后的值
+	expression string
+	oldValue   string
+	newValue   string
+	// outOfScope 观察点的变量已经离开作用域，gdb会自动删除该观察点
+	outOfScope bool
+	// 触发停止的线程，以及是否所有线程都已经停止
+	threadId          int
+	allThreadsStopped bool
+	// 程序收到信号时，信号的名称和含义，比如SIGSEGV, Segmentation fault
+	signalName    string
+	signalMeaning string
+	// exitCode 程序退出时的退出码
+	exitCode int
+}
+
+// exited 程序是否已经退出
+func (s *StoppedOutput) exited() bool {
+	return s.reason == constants.ExitedNormally || s.reason == constants.Exited || s.reason == constants.ExitedSignalled
+}
+
+// exitMessage 程序退出的描述
+func (s *StoppedOutput) exitMessage() string {
+	if s.reason == constants.ExitedSignalled {
+		return fmt.Sprintf("Program terminated with signal %s, %s.", s.signalName, s.signalMeaning)
+	}
+	return fmt.Sprintf("Program exited with code %d.", s.exitCode)
+}
+
+// description 停止原因的描述，观察点触发时描述值的变化
+func (s *StoppedOutput) description() string {
+	if s.reason == constants.NoHistoryStopped {
+		return "no more execution history"
+	}
+	if s.reason == constants.ExceptionStopped {
+		return fmt.Sprintf("%s, %s", s.signalName, s.signalMeaning)
+	}
+	if s.reason != constants.DataBreakpointStopped {
+		return ""
+	}
+	if s.outOfScope {
+		return fmt.Sprintf("watchpoint %s went out of scope", s.breakpointNumber)
+	}
+	if s.oldValue != "" {
+		return fmt.Sprintf("%s: %s -> %s", s.expression, s.oldValue, s.newValue)
+	}
+	return fmt.Sprintf("%s: %s", s.expression, s.newValue)
 }
 
 // ConvertVariableName 解析变量名称
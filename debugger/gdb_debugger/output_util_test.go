@@ -0,0 +1,165 @@
+package gdb_debugger
+
+import (
+	"testing"
+
+	"github.com/fansqz/go-debugger/constants"
+	"github.com/google/go-dap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWatchpointStoppedOutput(t *testing.T) {
+	util := NewGDBOutputUtil()
+	stopped := util.ParseStoppedEventOutput(map[string]interface{}{
+		"reason": "watchpoint-trigger",
+		"wpt":    map[string]interface{}{"number": "2", "exp": "p"},
+		"value":  map[string]interface{}{"old": "0x0", "new": "0x5555555592a0"},
+		"frame":  map[string]interface{}{"fullname": "/tmp/main.c", "line": "12"},
+	})
+	assert.Equal(t, constants.DataBreakpointStopped, stopped.reason)
+	assert.Equal(t, "2", stopped.breakpointNumber)
+	assert.Equal(t, 12, stopped.line)
+	assert.Equal(t, "p: 0x0 -> 0x5555555592a0", stopped.description())
+
+	stopped = util.ParseStoppedEventOutput(map[string]interface{}{
+		"reason":  "read-watchpoint-trigger",
+		"hw-rwpt": map[string]interface{}{"number": "3", "exp": "count"},
+		"value":   map[string]interface{}{"value": "7"},
+	})
+	assert.Equal(t, "3", stopped.breakpointNumber)
+	assert.Equal(t, "count: 7", stopped.description())
+}
+
+func TestParseWatchpointOutput(t *testing.T) {
+	util := NewGDBOutputUtil()
+	number, ok := util.ParseWatchpointOutput(map[string]interface{}{
+		"class":   "done",
+		"payload": map[string]interface{}{"hw-awpt": map[string]interface{}{"number": "4", "exp": "x"}},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "4", number)
+}
+
+func TestParseThreadsOutput(t *testing.T) {
+	util := NewGDBOutputUtil()
+	threads, ok := util.ParseThreadsOutput(map[string]interface{}{
+		"class": "done",
+		"payload": map[string]interface{}{
+			"threads": []interface{}{
+				map[string]interface{}{"id": "1", "target-id": "Thread 0x7ffff7d89740 (LWP 100)", "name": "main"},
+				map[string]interface{}{"id": "2", "target-id": "Thread 0x7ffff7588640 (LWP 101)"},
+			},
+			"current-thread-id": "1",
+		},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, []dap.Thread{
+		{Id: 1, Name: "main (Thread 0x7ffff7d89740 (LWP 100))"},
+		{Id: 2, Name: "Thread 0x7ffff7588640 (LWP 101)"},
+	}, threads)
+}
+
+func TestParseStoppedEventThread(t *testing.T) {
+	util := NewGDBOutputUtil()
+	stopped := util.ParseStoppedEventOutput(map[string]interface{}{
+		"reason":          "end-stepping-range",
+		"frame":           map[string]interface{}{"fullname": "/tmp/main.c", "line": "8"},
+		"thread-id":       "2",
+		"stopped-threads": "all",
+	})
+	assert.Equal(t, constants.StepStopped, stopped.reason)
+	assert.Equal(t, 2, stopped.threadId)
+	assert.True(t, stopped.allThreadsStopped)
+}
+
+func TestParseInterruptStoppedOutput(t *testing.T) {
+	util := NewGDBOutputUtil()
+	stopped := util.ParseStoppedEventOutput(map[string]interface{}{
+		"reason":      "signal-received",
+		"signal-name": "SIGINT",
+		"frame":       map[string]interface{}{"fullname": "/tmp/main.c", "line": "5"},
+		"thread-id":   "1",
+	})
+	assert.Equal(t, constants.PauseStopped, stopped.reason)
+	assert.Equal(t, 5, stopped.line)
+}
+
+func TestParseNoHistoryStoppedOutput(t *testing.T) {
+	util := NewGDBOutputUtil()
+	stopped := util.ParseStoppedEventOutput(map[string]interface{}{
+		"reason": "no-history",
+		"frame":  map[string]interface{}{"fullname": "/tmp/main.c", "line": "3"},
+	})
+	assert.Equal(t, constants.NoHistoryStopped, stopped.reason)
+	assert.Equal(t, 3, stopped.line)
+	assert.Equal(t, "no more execution history", stopped.description())
+}
+
+func TestParseReadMemoryOutput(t *testing.T) {
+	util := NewGDBOutputUtil()
+	address, data, ok := util.ParseReadMemoryOutput(map[string]interface{}{
+		"class": "done",
+		"payload": map[string]interface{}{
+			"memory": []interface{}{
+				map[string]interface{}{"begin": "0x1000", "offset": "0x0", "end": "0x1002", "contents": "0102"},
+				map[string]interface{}{"begin": "0x1002", "offset": "0x2", "end": "0x1003", "contents": "ff"},
+				// 不连续的内存块不会返回
+				map[string]interface{}{"begin": "0x2000", "offset": "0x1000", "end": "0x2001", "contents": "aa"},
+			},
+		},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, "0x1000", address)
+	assert.Equal(t, []byte{0x01, 0x02, 0xff}, data)
+}
+
+func TestParseSignalStoppedOutput(t *testing.T) {
+	util := NewGDBOutputUtil()
+	stopped := util.ParseStoppedEventOutput(map[string]interface{}{
+		"reason":         "signal-received",
+		"signal-name":    "SIGSEGV",
+		"signal-meaning": "Segmentation fault",
+		"frame":          map[string]interface{}{"fullname": "/tmp/main.c", "line": "9"},
+	})
+	assert.Equal(t, constants.ExceptionStopped, stopped.reason)
+	assert.Equal(t, "SIGSEGV", stopped.signalName)
+	assert.Equal(t, "SIGSEGV, Segmentation fault", stopped.description())
+}
+
+func TestParseExitedOutput(t *testing.T) {
+	util := NewGDBOutputUtil()
+	stopped := util.ParseStoppedEventOutput(map[string]interface{}{
+		"reason":    "exited",
+		"exit-code": "012",
+	})
+	assert.True(t, stopped.exited())
+	assert.Equal(t, 10, stopped.exitCode)
+	assert.Equal(t, "Program exited with code 10.", stopped.exitMessage())
+
+	stopped = util.ParseStoppedEventOutput(map[string]interface{}{
+		"reason":         "exited-signalled",
+		"signal-name":    "SIGSEGV",
+		"signal-meaning": "Segmentation fault",
+	})
+	assert.True(t, stopped.exited())
+	assert.Equal(t, 139, stopped.exitCode)
+	assert.Equal(t, "Program terminated with signal SIGSEGV, Segmentation fault.", stopped.exitMessage())
+
+	stopped = util.ParseStoppedEventOutput(map[string]interface{}{"reason": "exited-normally"})
+	assert.Equal(t, 0, stopped.exitCode)
+}
+
+func TestParseBkpt(t *testing.T) {
+	util := NewGDBOutputUtil()
+	output, ok := util.ParseBkpt(map[string]interface{}{
+		"bkpt": map[string]interface{}{"number": "3", "pending": "lib.c:10", "times": "0"},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, &BreakpointOutput{Number: "3", Pending: true}, output)
+
+	output, ok = util.ParseBkpt(map[string]interface{}{
+		"bkpt": map[string]interface{}{"number": "3", "fullname": "/tmp/lib.c", "line": "12"},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, &BreakpointOutput{Number: "3", File: "/tmp/lib.c", Line: 12}, output)
+}
@@ -0,0 +1,97 @@
+package gdb_debugger
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/fansqz/go-debugger/constants"
+)
+
+// signalNumbers 常见信号名称对应的信号值，用于计算被信号终止的程序的退出码
+var signalNumbers = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGILL":  syscall.SIGILL,
+	"SIGTRAP": syscall.SIGTRAP,
+	"SIGABRT": syscall.SIGABRT,
+	"SIGBUS":  syscall.SIGBUS,
+	"SIGFPE":  syscall.SIGFPE,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGSEGV": syscall.SIGSEGV,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGPIPE": syscall.SIGPIPE,
+	"SIGALRM": syscall.SIGALRM,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGXCPU": syscall.SIGXCPU,
+	"SIGXFSZ": syscall.SIGXFSZ,
+}
+
+// signalExitCode 被信号终止的程序的退出码，和shell一样使用128+信号值，未知信号返回-1
+func signalExitCode(signalName string) int {
+	if signal, ok := signalNumbers[signalName]; ok {
+		return 128 + int(signal)
+	}
+	return -1
+}
+
+// signalFilter 获取信号对应的异常断点过滤器，没有对应的过滤器时返回空
+func signalFilter(signalName string) constants.ExceptionFilterType {
+	switch signalName {
+	case "SIGSEGV", "SIGBUS":
+		return constants.SegfaultFilter
+	case "SIGABRT":
+		return constants.AbortFilter
+	case "SIGFPE":
+		return constants.FPEFilter
+	}
+	return ""
+}
+
+// shouldStopOnSignal 根据开启的异常断点过滤器，判断收到信号时是否需要停止
+func shouldStopOnSignal(filters map[constants.ExceptionFilterType]bool, signalName string) bool {
+	if filters[constants.AllSignalsFilter] {
+		return true
+	}
+	filter := signalFilter(signalName)
+	return filter != "" && filters[filter]
+}
+
+// handledSignals 由异常断点过滤器控制的信号
+// SIGINT用于暂停程序，SIGTRAP用于断点，SIGKILL无法捕获，这些信号保留gdb的默认设置
+var handledSignals = []string{
+	"SIGHUP", "SIGQUIT", "SIGILL", "SIGABRT", "SIGBUS", "SIGFPE", "SIGUSR1", "SIGSEGV",
+	"SIGUSR2", "SIGPIPE", "SIGALRM", "SIGTERM", "SIGCHLD", "SIGXCPU", "SIGXFSZ",
+}
+
+// signalHandleCommands 根据开启的异常断点过滤器生成gdb的handle命令
+// 需要停止的信号由gdb停止程序，其余的信号不停止也不打印，直接交给程序处理
+func signalHandleCommands(filters map[constants.ExceptionFilterType]bool) []string {
+	var stop, nostop []string
+	for _, signalName := range handledSignals {
+		if shouldStopOnSignal(filters, signalName) {
+			stop = append(stop, signalName)
+		} else {
+			nostop = append(nostop, signalName)
+		}
+	}
+	var commands []string
+	if len(stop) != 0 {
+		commands = append(commands, fmt.Sprintf("handle %s stop print", strings.Join(stop, " ")))
+	}
+	if len(nostop) != 0 {
+		commands = append(commands, fmt.Sprintf("handle %s nostop noprint", strings.Join(nostop, " ")))
+	}
+	return commands
+}
+
+// hasFaultingAddress 判断信号是否有出错的内存地址，只有这些信号的siginfo中si_addr有意义
+func hasFaultingAddress(signalName string) bool {
+	switch signalName {
+	case "SIGSEGV", "SIGBUS", "SIGFPE", "SIGILL":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package gdb_debugger
+
+import (
+	"testing"
+
+	"github.com/fansqz/go-debugger/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldStopOnSignal(t *testing.T) {
+	filters := map[constants.ExceptionFilterType]bool{
+		constants.SegfaultFilter: true,
+		constants.FPEFilter:      true,
+	}
+	assert.True(t, shouldStopOnSignal(filters, "SIGSEGV"))
+	assert.True(t, shouldStopOnSignal(filters, "SIGBUS"))
+	assert.True(t, shouldStopOnSignal(filters, "SIGFPE"))
+	assert.False(t, shouldStopOnSignal(filters, "SIGABRT"))
+	assert.False(t, shouldStopOnSignal(filters, "SIGUSR1"))
+
+	filters[constants.AllSignalsFilter] = true
+	assert.True(t, shouldStopOnSignal(filters, "SIGUSR1"))
+	assert.True(t, shouldStopOnSignal(filters, "SIGABRT"))
+}
+
+func TestSignalHandleCommands(t *testing.T) {
+	commands := signalHandleCommands(map[constants.ExceptionFilterType]bool{
+		constants.SegfaultFilter: true,
+		constants.AbortFilter:    true,
+	})
+	assert.Equal(t, []string{
+		"handle SIGABRT SIGBUS SIGSEGV stop print",
+		"handle SIGHUP SIGQUIT SIGILL SIGFPE SIGUSR1 SIGUSR2 SIGPIPE SIGALRM SIGTERM SIGCHLD SIGXCPU SIGXFSZ nostop noprint",
+	}, commands)
+
+	// all过滤器停止所有信号
+	commands = signalHandleCommands(map[constants.ExceptionFilterType]bool{constants.AllSignalsFilter: true})
+	assert.Equal(t, 1, len(commands))
+	assert.Contains(t, commands[0], "SIGUSR1")
+	assert.Contains(t, commands[0], "stop print")
+}
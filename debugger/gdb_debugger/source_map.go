@@ -0,0 +1,49 @@
+package gdb_debugger
+
+import (
+	"path/filepath"
+
+	"github.com/google/go-dap"
+)
+
+// sourceMap 服务端源文件路径和客户端路径之间的映射
+// 服务端编译的用户代码位于临时目录中，gdb只认识服务端的路径，客户端只认识自己的路径
+type sourceMap struct {
+	toClient map[string]string
+	toServer map[string]string
+}
+
+// newSourceMap 根据服务端路径到客户端路径的映射创建sourceMap
+func newSourceMap(paths map[string]string) sourceMap {
+	answer := sourceMap{
+		toClient: map[string]string{},
+		toServer: map[string]string{},
+	}
+	for server, client := range paths {
+		answer.toClient[server] = client
+		answer.toServer[client] = server
+	}
+	return answer
+}
+
+// clientPath 把gdb返回的服务端路径转换成客户端的路径，没有映射时原样返回
+func (s sourceMap) clientPath(path string) string {
+	if client, ok := s.toClient[path]; ok {
+		return client
+	}
+	return path
+}
+
+// serverPath 把客户端的路径转换成gdb使用的服务端路径，没有映射时原样返回
+func (s sourceMap) serverPath(path string) string {
+	if server, ok := s.toServer[path]; ok {
+		return server
+	}
+	return path
+}
+
+// clientSource 根据服务端路径创建返回给客户端的源文件
+func (s sourceMap) clientSource(path string) *dap.Source {
+	path = s.clientPath(path)
+	return &dap.Source{Name: filepath.Base(path), Path: path}
+}
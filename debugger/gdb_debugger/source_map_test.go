@@ -0,0 +1,23 @@
+package gdb_debugger
+
+import (
+	"testing"
+
+	"github.com/google/go-dap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceMap(t *testing.T) {
+	sources := newSourceMap(map[string]string{"/var/fanCode/tempDir/1/main.c": "/home/user/hello.c"})
+	assert.Equal(t, "/var/fanCode/tempDir/1/main.c", sources.serverPath("/home/user/hello.c"))
+	assert.Equal(t, "/home/user/hello.c", sources.clientPath("/var/fanCode/tempDir/1/main.c"))
+	assert.Equal(t, &dap.Source{Name: "hello.c", Path: "/home/user/hello.c"}, sources.clientSource("/var/fanCode/tempDir/1/main.c"))
+
+	// 没有映射的路径原样返回
+	assert.Equal(t, "/usr/include/stdio.h", sources.clientPath("/usr/include/stdio.h"))
+	assert.Equal(t, "other.c", sources.serverPath("other.c"))
+
+	// 没有设置映射时也可以使用
+	var empty sourceMap
+	assert.Equal(t, "main.c", empty.serverPath("main.c"))
+}
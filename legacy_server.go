@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/fansqz/go-debugger/constants"
+	"github.com/fansqz/go-debugger/debugger"
+	e "github.com/fansqz/go-debugger/error"
+	"github.com/fansqz/go-debugger/protocol"
+	"github.com/google/go-dap"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LegacySession 使用protocol包中定义的JSON协议的调试会话，服务于DAP之前的旧版前端
+// 旧协议只调试一份用户代码，断点用代码行号表示，变量引用和栈帧id使用字符串
+type LegacySession struct {
+	conn messageConn
+	// remote 客户端的地址，用于日志
+	remote string
+	// sendLock 响应和事件在不同的协程中发送，需要保证每条消息完整写入
+	sendLock sync.Mutex
+
+	// debuggerLock 请求协程和事件协程都会调用调试器，持有该锁时才能读写debugger以及调用调试器，
+	// 保证发送给gdb的命令串行执行
+	debuggerLock sync.Mutex
+	debugger     debugger.Debugger
+	// workPath 编译用户代码的临时目录，会话结束时删除
+	workPath string
+	// source 用户代码的文件，设置断点时使用
+	source dap.Source
+	// breakpoints 当前设置的断点行号
+	breakpoints map[int]bool
+
+	// events 调试器产生的事件，由单独的协程按顺序转换成旧协议的事件
+	// 转换stopped事件需要获取栈帧，不能在gdb输出协程中进行
+	events chan dap.EventMessage
+	done   chan struct{}
+}
+
+// serveLegacySession 在连接上运行一个旧协议的调试会话，直到连接断开
+func serveLegacySession(conn messageConn, remote string) {
+	session := &LegacySession{
+		conn:        conn,
+		remote:      remote,
+		breakpoints: map[int]bool{},
+		events:      make(chan dap.EventMessage, 1024),
+		done:        make(chan struct{}),
+	}
+	go session.processEvents()
+	defer session.close()
+
+	for {
+		content, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+				log.Printf("Connection error: %v\n", err)
+				break
+			}
+			log.Printf("Server error: %v\n", err)
+			continue
+		}
+		session.dispatchRequest(content)
+	}
+}
+
+// close 关闭会话，终止调试器并关闭连接
+func (l *LegacySession) close() {
+	log.Printf("Closing connection from %s\n", l.remote)
+	l.debuggerLock.Lock()
+	l.terminateDebugger()
+	l.debuggerLock.Unlock()
+	close(l.done)
+	_ = l.conn.Close()
+}
+
+// terminateDebugger 终止当前的调试器，并清理编译产生的临时文件，调用时需要持有debuggerLock
+func (l *LegacySession) terminateDebugger() {
+	debug := l.debugger
+	l.debugger = nil
+	if debug != nil {
+		if err := debug.Terminate(); err != nil {
+			log.Printf("Terminate debugger fail, err = %s\n", err)
+		}
+	}
+	if l.workPath != "" {
+		_ = os.RemoveAll(l.workPath)
+		l.workPath = ""
+	}
+	l.breakpoints = map[int]bool{}
+}
+
+func (l *LegacySession) dispatchRequest(content []byte) {
+	var base protocol.BaseRequest
+	if err := json.Unmarshal(content, &base); err != nil {
+		log.Printf("Unable to decode request, err = %s\n", err)
+		return
+	}
+	data, err := l.handleRequest(base, content)
+	l.sendResponse(base.Sequence, data, err)
+}
+
+// handleRequest 持有debuggerLock处理请求，返回响应的数据
+func (l *LegacySession) handleRequest(base protocol.BaseRequest, content []byte) (interface{}, error) {
+	l.debuggerLock.Lock()
+	defer l.debuggerLock.Unlock()
+	// 启动调试之前只能处理start和terminate请求
+	if l.debugger == nil && base.Type != constants.StartDebug && base.Type != constants.Terminate {
+		return nil, errors.New("no debug is running")
+	}
+	var data interface{}
+	var err error
+	switch base.Type {
+	case constants.StartDebug:
+		var request protocol.StartDebugRequest
+		if err = json.Unmarshal(content, &request); err == nil {
+			err = l.onStartDebugRequest(&request)
+		}
+	case constants.SendToConsole:
+		var request protocol.SendToConsoleRequest
+		if err = json.Unmarshal(content, &request); err == nil {
+			// 旧协议的输入是一行内容，没有换行时补上，用户程序按行读取输入
+			input := request.Content
+			if !strings.HasSuffix(input, "\n") {
+				input += "\n"
+			}
+			err = l.debugger.SendToConsole(input, false)
+		}
+	case constants.Step:
+		var request protocol.StepRequest
+		if err = json.Unmarshal(content, &request); err == nil {
+			err = l.onStepRequest(&request)
+		}
+	case constants.Continue:
+		err = l.debugger.Continue(0)
+	case constants.AddBreakpoints:
+		var request protocol.AddBreakpointRequest
+		if err = json.Unmarshal(content, &request); err == nil {
+			data, err = l.updateBreakpoints(request.Breakpoints, true)
+		}
+	case constants.RemoveBreakpoints:
+		var request protocol.RemoveBreakpointRequest
+		if err = json.Unmarshal(content, &request); err == nil {
+			data, err = l.updateBreakpoints(request.Breakpoints, false)
+		}
+	case constants.Terminate:
+		l.terminateDebugger()
+	case constants.StackTrace:
+		data, err = l.onStackTraceRequest()
+	case constants.FrameVariables:
+		var request protocol.GetFrameVariables
+		if err = json.Unmarshal(content, &request); err == nil {
+			data, err = l.onFrameVariablesRequest(&request)
+		}
+	case constants.Variables:
+		var request protocol.GetVariablesRequest
+		if err = json.Unmarshal(content, &request); err == nil {
+			data, err = l.onVariablesRequest(&request)
+		}
+	default:
+		err = fmt.Errorf("%s is not yet supported", base.Type)
+	}
+	return data, err
+}
+
+// onStartDebugRequest 编译用户代码，启动调试器，设置初始断点以后开始运行
+func (l *LegacySession) onStartDebugRequest(request *protocol.StartDebugRequest) error {
+	language := string(request.Language)
+	if language == "" {
+		language = string(constants.LanguageC)
+	}
+	// 同一个会话重复启动时，先结束上一次调试
+	l.terminateDebugger()
+
+	l.workPath = newWorkPath()
+	execFile, output, err := compileFile(l.workPath, language, request.Code)
+	output = clientCompileOutput(output, l.workPath, language, "")
+	if err != nil {
+		message := output
+		if message == "" {
+			message = err.Error()
+		}
+		l.send(&protocol.CompileEvent{Event: constants.CompileEvent, Success: false, Message: message})
+		if output == "" {
+			return err
+		}
+		return e.ErrCompileFailed
+	}
+	l.send(&protocol.CompileEvent{Event: constants.CompileEvent, Success: true, Message: debugger.CompileSuccessEvent.Message})
+
+	debug, err := createDebugger(language, &debugger.StartOption{
+		ExecFile: execFile,
+		MainCode: request.Code,
+		Callback: l.onDebuggerEvent,
+	})
+	if err != nil {
+		if debug != nil {
+			_ = debug.Terminate()
+		}
+		l.send(&protocol.LaunchEvent{Event: constants.LaunchEvent, Success: false, Message: debugger.LaunchFailEvent.Message})
+		return err
+	}
+	l.debugger = debug
+	l.source = dap.Source{Path: mainSourceName(language)}
+	l.send(&protocol.LaunchEvent{Event: constants.LaunchEvent, Success: true, Message: debugger.LaunchSuccessEvent.Message})
+
+	if _, err = l.updateBreakpoints(request.Breakpoints, true); err != nil {
+		return err
+	}
+	return l.debugger.Run()
+}
+
+func (l *LegacySession) onStepRequest(request *protocol.StepRequest) error {
+	switch request.StepType {
+	case constants.StepIn:
+		return l.debugger.StepIn(0, "")
+	case constants.StepOut:
+		return l.debugger.StepOut(0)
+	case constants.StepOver:
+		return l.debugger.StepOver(0, "")
+	}
+	return fmt.Errorf("unknown step type %s", request.StepType)
+}
+
+// updateBreakpoints 添加或者移除断点，旧协议每次只传递变化的行号，需要和已有的断点合并以后一起设置
+// 返回设置以后所有断点的行号
+func (l *LegacySession) updateBreakpoints(lines []int, add bool) ([]int, error) {
+	for _, line := range lines {
+		if add {
+			l.breakpoints[line] = true
+		} else {
+			delete(l.breakpoints, line)
+		}
+	}
+	all := make([]int, 0, len(l.breakpoints))
+	for line := range l.breakpoints {
+		all = append(all, line)
+	}
+	sort.Ints(all)
+	breakpoints := make([]dap.SourceBreakpoint, 0, len(all))
+	for _, line := range all {
+		breakpoints = append(breakpoints, dap.SourceBreakpoint{Line: line})
+	}
+	if _, err := l.debugger.SetBreakpoints(l.source, breakpoints); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (l *LegacySession) onStackTraceRequest() ([]*debugger.StackFrame, error) {
+	frames, err := l.debugger.GetStackTrace(0)
+	if err != nil {
+		return nil, err
+	}
+	answer := make([]*debugger.StackFrame, 0, len(frames))
+	for _, frame := range frames {
+		stackFrame := &debugger.StackFrame{
+			ID:   strconv.Itoa(frame.Id),
+			Name: frame.Name,
+			Line: frame.Line,
+		}
+		if frame.Source != nil {
+			stackFrame.Path = frame.Source.Path
+		}
+		answer = append(answer, stackFrame)
+	}
+	return answer, nil
+}
+
+// onFrameVariablesRequest 获取栈帧的局部变量
+func (l *LegacySession) onFrameVariablesRequest(request *protocol.GetFrameVariables) ([]*debugger.Variable, error) {
+	frameId, err := strconv.Atoi(request.FrameID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid frameID %s", request.FrameID)
+	}
+	scopes, err := l.debugger.GetScopes(frameId)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		if scope.Name == "Local" {
+			return l.getVariables(scope.VariablesReference)
+		}
+	}
+	return []*debugger.Variable{}, nil
+}
+
+func (l *LegacySession) onVariablesRequest(request *protocol.GetVariablesRequest) ([]*debugger.Variable, error) {
+	reference, err := strconv.Atoi(string(request.Reference))
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %s", request.Reference)
+	}
+	return l.getVariables(reference)
+}
+
+// getVariables 获取引用对应的变量，并转换成旧协议的变量
+func (l *LegacySession) getVariables(reference int) ([]*debugger.Variable, error) {
+	variables, err := l.debugger.GetVariables(reference)
+	if err != nil {
+		return nil, err
+	}
+	answer := make([]*debugger.Variable, 0, len(variables))
+	for _, variable := range variables {
+		value := variable.Value
+		v := &debugger.Variable{
+			Name:           variable.Name,
+			Type:           variable.Type,
+			Value:          &value,
+			ChildrenNumber: variable.IndexedVariables + variable.NamedVariables,
+		}
+		if variable.VariablesReference != 0 {
+			v.Reference = strconv.Itoa(variable.VariablesReference)
+		}
+		answer = append(answer, v)
+	}
+	return answer, nil
+}
+
+// onDebuggerEvent 调试器产生的事件回调，在gdb输出协程中调用，不能阻塞
+func (l *LegacySession) onDebuggerEvent(event dap.EventMessage) {
+	select {
+	case l.events <- event:
+	case <-l.done:
+	}
+}
+
+// processEvents 按顺序把调试器的事件转换成旧协议的事件发送给客户端
+func (l *LegacySession) processEvents() {
+	for {
+		select {
+		case event := <-l.events:
+			if message := l.convertEvent(event); message != nil {
+				l.send(message)
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// convertEvent 把DAP事件转换成旧协议的事件，旧协议没有对应事件时返回nil
+func (l *LegacySession) convertEvent(event dap.EventMessage) interface{} {
+	switch event := event.(type) {
+	case *dap.StoppedEvent:
+		return &protocol.StoppedEvent{
+			Event:  constants.StoppedEvent,
+			Reason: constants.StoppedReasonType(event.Body.Reason),
+			Line:   l.stoppedLine(event.Body.ThreadId),
+		}
+	case *dap.ContinuedEvent:
+		return &protocol.ContinuedEvent{Event: constants.ContinuedEvent}
+	case *dap.OutputEvent:
+		return &protocol.OutputEvent{Event: constants.OutputEvent, Output: event.Body.Output}
+	case *dap.BreakpointEvent:
+		return &protocol.BreakpointEvent{
+			Event:       constants.BreakpointEvent,
+			Reason:      legacyBreakpointReason(event.Body.Reason),
+			Breakpoints: []int{event.Body.Breakpoint.Line},
+		}
+	case *debugger.ExitedEventMessage:
+		return &protocol.ExitedEvent{
+			Event:    constants.ExitedEvent,
+			ExitCode: event.Body.ExitCode,
+			Message:  event.Body.Message,
+		}
+	case *dap.TerminatedEvent:
+		return &protocol.TerminatedEvent{Event: constants.TerminatedEvent}
+	}
+	return nil
+}
+
+// legacyBreakpointReason 把DAP断点事件的原因转换成旧协议的原因，旧协议中断点改变为change
+func legacyBreakpointReason(reason string) constants.BreakpointReasonType {
+	if reason == "changed" {
+		return constants.ChangeType
+	}
+	return constants.BreakpointReasonType(reason)
+}
+
+// stoppedLine 获取程序停止的行号，获取失败时返回0
+func (l *LegacySession) stoppedLine(threadId int) int {
+	// 和请求协程的调试器调用串行执行
+	l.debuggerLock.Lock()
+	defer l.debuggerLock.Unlock()
+	if l.debugger == nil {
+		return 0
+	}
+	frames, err := l.debugger.GetStackTrace(threadId)
+	if err != nil || len(frames) == 0 {
+		return 0
+	}
+	return frames[0].Line
+}
+
+// sendResponse 发送请求的响应，err不为nil时表示请求失败
+func (l *LegacySession) sendResponse(sequence uint, data interface{}, err error) {
+	response := &protocol.Response{
+		Sequence: sequence,
+		Success:  err == nil,
+		Data:     data,
+	}
+	if err != nil {
+		response.Message = err.Error()
+	}
+	l.send(response)
+}
+
+func (l *LegacySession) send(message interface{}) {
+	content, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Marshal message fail, err = %s\n", err)
+		return
+	}
+	l.sendLock.Lock()
+	defer l.sendLock.Unlock()
+	if err = l.conn.WriteMessage(content); err != nil {
+		log.Printf("Send message fail, err = %s\n", err)
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/fansqz/go-debugger/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacyBreakpointReason(t *testing.T) {
+	// 旧协议的客户端使用change表示断点改变
+	assert.Equal(t, constants.ChangeType, legacyBreakpointReason("changed"))
+	assert.Equal(t, constants.NewType, legacyBreakpointReason("new"))
+	assert.Equal(t, constants.RemovedType, legacyBreakpointReason("removed"))
+}
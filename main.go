@@ -8,18 +8,18 @@ import (
 	"github.com/fansqz/go-debugger/debugger/c_debugger"
 	"github.com/fansqz/go-debugger/debugger/cpp_debugger"
 	"github.com/fansqz/go-debugger/utils"
-	"github.com/google/go-dap"
 	"log"
 	"net"
-	"os"
 	"path"
+	"strings"
 )
 
-var ConnList []net.Conn
-
 // 定义版本号
 const Version = "1.0.1"
 
+// workDir 编译用户代码时使用的临时目录
+const workDir = "/var/fanCode/tempDir"
+
 func main() {
 	//启动日志
 	SetupLogger()
@@ -27,9 +27,10 @@ func main() {
 
 	showVersion := flag.Bool("version", false, "Show the version number")
 	port := flag.String("port", "8889", "TCP port to listen on")
-	execFile := flag.String("file", "", "Exec file")
-	codeFile := flag.String("codeFile", "", "main file code")
-	language := flag.String("language", "c", "Program language")
+	stdio := flag.Bool("stdio", false, "Speak DAP over stdin/stdout instead of listening on a port")
+	protocolName := flag.String("protocol", "dap", "Protocol spoken by sessions: dap, or legacy for the JSON protocol in package protocol")
+	wsPort := flag.String("ws-port", "", "Port to serve DAP over WebSocket at "+webSocketPath+", disabled if empty")
+	allowedOrigins := flag.String("allowed-origins", "", "Comma separated origins allowed to open WebSocket sessions, * allows any origin, browsers are rejected if empty")
 	flag.Parse()
 
 	// 检查是否需要显示版本信息
@@ -37,21 +38,39 @@ func main() {
 		fmt.Printf("Version: %s\n", Version)
 		return
 	}
-	if language == nil || *language == "" {
-		fmt.Println("language cannot be empty")
+
+	switch *protocolName {
+	case "dap":
+	case "legacy":
+		sessionHandler = serveLegacySession
+	default:
+		fmt.Printf("unknown protocol: %s\n", *protocolName)
 		return
 	}
-	var code string
-	if codeFile != nil && *codeFile != "" {
-		// 编译文件
-		data, _ := os.ReadFile(*codeFile)
-		code = string(data)
-	}
-	if execFile == nil || *execFile == "" {
-		fmt.Println("exec file cannot be empty")
+
+	// 作为编辑器的debug adapter运行，只服务一个会话
+	if *stdio {
+		handleStdio()
 		return
 	}
 
+	// 浏览器客户端通过WebSocket连接
+	if *wsPort != "" {
+		origins := strings.FieldsFunc(*allowedOrigins, func(r rune) bool { return r == ',' || r == ' ' })
+		wsListener, err := net.Listen("tcp", ":"+*wsPort)
+		if err != nil {
+			fmt.Printf("websocket listening at: %s fail, err = %s\n", *wsPort, err)
+			return
+		}
+		defer wsListener.Close()
+		go func() {
+			if err := serveWebSocket(wsListener, origins); err != nil {
+				log.Printf("WebSocket server fail, err = %s\n", err)
+			}
+		}()
+		fmt.Printf("started websocket listening at: %s%s\n", wsListener.Addr().String(), webSocketPath)
+	}
+
 	// 监听端口
 	listener, err := net.Listen("tcp", ":"+*port)
 	if err != nil {
@@ -61,55 +80,74 @@ func main() {
 	defer listener.Close()
 	fmt.Printf("started listening at: %s\n", listener.Addr().String())
 
-	// 启动调试器
-	debug, err := createDebugger(*language, *execFile, code)
-	if err != nil {
-		log.Printf("start debug fail, err = %s\n", err)
-		return
-	}
-
 	for {
 		conn, err := listener.Accept()
-		ConnList = append(ConnList, conn)
 		if err != nil {
 			log.Printf("Connection failed: %v\n", err)
 			continue
 		}
-		// Handle multiple client connections concurrently
-		go handleConnection(conn, debug)
+		// 每个连接拥有独立的调试会话，调试目标由客户端的launch/attach请求指定
+		go handleConnection(conn)
 	}
 }
 
-// createDebugger 创建调试器
-func createDebugger(language string, execFile string, code string) (debugger.Debugger, error) {
+// createDebugger 创建调试器并启动gdb
+func createDebugger(language string, option *debugger.StartOption) (debugger.Debugger, error) {
 	var d debugger.Debugger
 	switch language {
 	case string(constants.LanguageC):
 		d = c_debugger.NewCDebugger()
 	case string(constants.LanguageCpp):
 		d = cpp_debugger.NewCPPDebugger()
+	default:
+		return nil, fmt.Errorf("language %s not support", language)
 	}
-	err := d.Start(&debugger.StartOption{
-		ExecFile: execFile,
-		MainCode: code,
-		Callback: func(event dap.EventMessage) {
-			for _, conn := range ConnList {
-				dap.WriteProtocolMessage(conn, event)
-			}
-		},
-	})
+	err := d.Start(option)
 	return d, err
 }
 
-// 编译文件
-func compileFile(language string, code string) (string, error) {
+// 编译文件，返回可执行文件路径以及编译器输出
+func compileFile(workPath string, language string, code string) (string, string, error) {
 	switch language {
 	case string(constants.LanguageC):
-		workPath := path.Join("/var/fanCode/tempDir", utils.GetUUID())
 		return c_debugger.CompileCFile(workPath, code)
 	case string(constants.LanguageCpp):
-		workPath := path.Join("/var/fanCode/tempDir", utils.GetUUID())
 		return cpp_debugger.CompileCPPFile(workPath, code)
 	}
-	return "", fmt.Errorf("language not support")
+	return "", "", fmt.Errorf("language not support")
+}
+
+// mainSourceName 服务端编译时用户代码的文件名
+func mainSourceName(language string) string {
+	if language == string(constants.LanguageCpp) {
+		return "main.cpp"
+	}
+	return "main.c"
+}
+
+// clientSourcePath 用户代码在客户端的路径，客户端没有提供时使用main.c或main.cpp
+func clientSourcePath(language string, sourcePath string) string {
+	if sourcePath == "" {
+		return mainSourceName(language)
+	}
+	return sourcePath
+}
+
+// clientSourceMap 服务端编译的源文件到客户端路径的映射，调试器返回的源文件使用客户端的路径
+func clientSourceMap(workPath string, language string, sourcePath string) map[string]string {
+	return map[string]string{
+		path.Join(workPath, mainSourceName(language)): clientSourcePath(language, sourcePath),
+	}
+}
+
+// clientCompileOutput 把编译输出中服务端临时目录下的路径替换成客户端的路径，
+// 避免泄露服务端的目录结构，客户端也可以根据路径把诊断信息对应到自己的文件
+func clientCompileOutput(output string, workPath string, language string, sourcePath string) string {
+	output = strings.ReplaceAll(output, path.Join(workPath, mainSourceName(language)), clientSourcePath(language, sourcePath))
+	return strings.ReplaceAll(output, workPath+"/", "")
+}
+
+// newWorkPath 创建一个新的编译目录路径
+func newWorkPath() string {
+	return path.Join(workDir, utils.GetUUID())
 }
@@ -1,13 +1,18 @@
 package main
 
 import (
-	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/fansqz/go-debugger/constants"
 	"github.com/fansqz/go-debugger/debugger"
+	e "github.com/fansqz/go-debugger/error"
 	"github.com/google/go-dap"
 	"io"
 	"log"
 	"net"
+	"os"
 	"sync"
 )
 
@@ -16,34 +21,105 @@ import (
 // to per-request processing goroutines. It also launches the
 // sender goroutine to send resulting messages over the connection
 // back to the client.
-func handleConnection(conn net.Conn, d debugger.Debugger) {
-	// 创建调试session
-	debugSession := DebugSession{
+// 每个连接都会创建独立的DebugSession，拥有自己的调试器和gdb进程，连接断开时清理所有资源
+func handleConnection(conn net.Conn) {
+	sessionHandler(newStreamConn(conn), conn.RemoteAddr().String())
+}
+
+// handleStdio 通过进程的标准输入输出与客户端通信，用于作为编辑器的debug adapter可执行文件
+// 标准输出只能写入协议消息，日志需要写入文件或者标准错误
+func handleStdio() {
+	sessionHandler(newStreamConn(stdioConn{Reader: os.Stdin, Writer: os.Stdout}), "stdio")
+}
+
+// sessionHandler 在连接上运行会话的函数，由启动参数选择DAP或者旧版JSON协议
+var sessionHandler = serveSession
+
+// newDebugSession 创建连接上的调试会话，需要启动sendFromQueue协程以后才能发送消息
+func newDebugSession(conn messageConn, remote string) *DebugSession {
+	return &DebugSession{
 		conn:      conn,
-		debugger:  d,
-		rw:        bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn)),
-		sendQueue: make(chan dap.Message),
+		remote:    remote,
+		sendQueue: make(chan dap.Message, sendQueueSize),
+		sendDone:  make(chan struct{}),
 	}
+}
+
+// serveSession 在连接上运行一个调试会话，直到连接断开
+func serveSession(conn messageConn, remote string) {
+	// 创建调试session
+	debugSession := newDebugSession(conn, remote)
+	go debugSession.sendFromQueue()
+	defer debugSession.close()
 
 	for {
 		err := debugSession.handleRequest()
 		if err != nil {
 			if err == io.EOF {
-				log.Printf("No more data to read:", err)
+				log.Printf("No more data to read: %v\n", err)
+				break
+			}
+			// 连接已经断开，结束会话
+			var netErr net.Error
+			if errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+				log.Printf("Connection error: %v\n", err)
 				break
 			}
-			log.Printf("Server error: ", err)
+			log.Printf("Server error: %v\n", err)
 		}
 	}
+}
 
-	log.Printf("Closing connection from", conn.RemoteAddr())
-	debugSession.sendWg.Wait()
-	close(debugSession.sendQueue)
-	conn.Close()
+// close 关闭会话，终止调试器，等待已经发送的消息写入以后关闭连接
+func (d *DebugSession) close() {
+	log.Printf("Closing connection from %s\n", d.remote)
+	d.terminateDebugger()
+	// 调试器的协程可能还在发送事件，标记关闭以后不再接收新的消息
+	d.sendLock.Lock()
+	d.sendClosed = true
+	d.sendLock.Unlock()
+	d.sendWg.Wait()
+	close(d.sendQueue)
+	<-d.sendDone
+	_ = d.conn.Close()
+}
+
+// terminateDebugger 终止当前的调试器，并清理编译产生的临时文件
+func (d *DebugSession) terminateDebugger() {
+	if d.debugger != nil {
+		if err := d.debugger.Terminate(); err != nil {
+			log.Printf("Terminate debugger fail, err = %s\n", err)
+		}
+		d.debugger = nil
+	}
+	if d.workPath != "" {
+		_ = os.RemoveAll(d.workPath)
+		d.workPath = ""
+	}
+}
+
+// onDebuggerEvent 调试器产生的事件回调，转发给当前会话的客户端
+func (d *DebugSession) onDebuggerEvent(event dap.EventMessage) {
+	d.send(event)
+}
+
+// codec 解码客户端的消息，除了DAP定义的请求，还注册了自定义请求
+var codec = newCodec()
+
+func newCodec() *dap.Codec {
+	c := dap.NewCodec()
+	_ = c.RegisterRequest(string(constants.SendToConsole),
+		func() dap.Message { return &SendToConsoleRequest{} },
+		func() dap.Message { return &SendToConsoleResponse{} })
+	return c
 }
 
 func (d *DebugSession) handleRequest() error {
-	request, err := dap.ReadProtocolMessage(d.rw.Reader)
+	content, err := d.conn.ReadMessage()
+	if err != nil {
+		return err
+	}
+	request, err := codec.DecodeMessage(content)
 	if err != nil {
 		return err
 	}
@@ -52,50 +128,142 @@ func (d *DebugSession) handleRequest() error {
 }
 
 func (d *DebugSession) dispatchRequest(request dap.Message) {
+	// 启动调试之前只能处理初始化相关的请求
+	if d.debugger == nil {
+		switch request.(type) {
+		case *dap.InitializeRequest, *dap.LaunchRequest, *dap.AttachRequest, *dap.DisconnectRequest:
+		default:
+			if req, ok := request.(dap.RequestMessage); ok {
+				r := req.GetRequest()
+				d.sendErrorResponseWithOpts(*r, constants.NoDebugIsRunning, "no debug is running", "launch or attach first", false)
+			}
+			return
+		}
+	}
 	switch request := request.(type) {
 	case *dap.InitializeRequest:
 		d.onInitializeRequest(request)
+	case *dap.LaunchRequest:
+		d.onLaunchRequest(request)
+	case *dap.AttachRequest:
+		d.onAttachRequest(request)
 	case *dap.TerminateRequest:
 		d.onTerminateRequest(request)
+	case *dap.DisconnectRequest:
+		d.onDisconnectRequest(request)
 	case *dap.SetBreakpointsRequest:
 		d.onSetBreakpointsRequest(request)
+	case *dap.SetFunctionBreakpointsRequest:
+		d.onSetFunctionBreakpointsRequest(request)
+	case *dap.DataBreakpointInfoRequest:
+		d.onDataBreakpointInfoRequest(request)
+	case *dap.SetDataBreakpointsRequest:
+		d.onSetDataBreakpointsRequest(request)
+	case *dap.SetExceptionBreakpointsRequest:
+		d.onSetExceptionBreakpointsRequest(request)
+	case *dap.ExceptionInfoRequest:
+		d.onExceptionInfoRequest(request)
 	case *dap.ConfigurationDoneRequest:
 		d.onConfigurationDoneRequest(request)
 	case *dap.ContinueRequest:
 		d.onContinueRequest(request)
+	case *dap.PauseRequest:
+		d.onPauseRequest(request)
+	case *dap.StepBackRequest:
+		d.onStepBackRequest(request)
+	case *dap.ReverseContinueRequest:
+		d.onReverseContinueRequest(request)
 	case *dap.NextRequest:
 		d.onNextRequest(request)
 	case *dap.StepInRequest:
 		d.onStepInRequest(request)
 	case *dap.StepOutRequest:
 		d.onStepOutRequest(request)
+	case *dap.ThreadsRequest:
+		d.onThreadsRequest(request)
 	case *dap.StackTraceRequest:
 		d.onStackTraceRequest(request)
+	case *dap.DisassembleRequest:
+		d.onDisassembleRequest(request)
+	case *dap.ReadMemoryRequest:
+		d.onReadMemoryRequest(request)
+	case *dap.WriteMemoryRequest:
+		d.onWriteMemoryRequest(request)
 	case *dap.ScopesRequest:
 		d.onScopesRequest(request)
 	case *dap.VariablesRequest:
 		d.onVariablesRequest(request)
+	case *dap.EvaluateRequest:
+		d.onEvaluateRequest(request)
+	case *dap.SetVariableRequest:
+		d.onSetVariableRequest(request)
+	case *dap.SetExpressionRequest:
+		d.onSetExpressionRequest(request)
+	case *SendToConsoleRequest:
+		d.onSendToConsoleRequest(request)
 	default:
 		if baseReq, ok := request.(*dap.Request); ok {
-			d.send(newErrorResponse(baseReq.Seq, baseReq.Command, fmt.Sprint("%s is not yet supported", baseReq.Command)))
+			d.send(newErrorResponse(baseReq.Seq, baseReq.Command, fmt.Sprintf("%s is not yet supported", baseReq.Command)))
 		}
-		fmt.Printf("Unable to process %#v", request)
+		log.Printf("Unable to process %#v\n", request)
 	}
 }
 
-// send Message响应给客户端
+// sendQueueSize 等待写入连接的消息数量，超出时发送方等待
+const sendQueueSize = 64
+
+// send 把消息放入发送队列，由sendFromQueue写入连接
+// 请求处理和调试器事件回调都会调用，消息按照调用的顺序发送
 func (d *DebugSession) send(message dap.Message) {
-	dap.WriteProtocolMessage(d.rw.Writer, message)
-	d.rw.Flush()
+	d.sendLock.RLock()
+	if d.sendClosed {
+		d.sendLock.RUnlock()
+		return
+	}
+	d.sendWg.Add(1)
+	d.sendLock.RUnlock()
+	d.sendQueue <- message
+	d.sendWg.Done()
+}
+
+// sendFromQueue 唯一写连接的协程，写入之前为消息分配递增的seq
+func (d *DebugSession) sendFromQueue() {
+	defer close(d.sendDone)
+	seq := 0
+	for message := range d.sendQueue {
+		seq++
+		setSeq(message, seq)
+		content, err := json.Marshal(message)
+		if err != nil {
+			log.Printf("Marshal message fail, err = %s\n", err)
+			continue
+		}
+		if err = d.conn.WriteMessage(content); err != nil {
+			log.Printf("Send message fail, err = %s\n", err)
+		}
+	}
+}
+
+// setSeq 设置服务端发送的响应或者事件的seq
+func setSeq(message dap.Message, seq int) {
+	switch message := message.(type) {
+	case dap.ResponseMessage:
+		message.GetResponse().Seq = seq
+	case dap.EventMessage:
+		message.GetEvent().Seq = seq
+	}
 }
 
 // DebugSession 调试会话
 type DebugSession struct {
-	conn net.Conn
-	// rw is used to read requests and write events/responses
-	rw *bufio.ReadWriter
+	// conn is used to read requests and write events/responses
+	conn messageConn
+	// remote 客户端的地址，用于日志
+	remote string
 
 	debugger debugger.Debugger
+	// workPath 编译用户代码的临时目录，会话结束时删除
+	workPath string
 	// sendQueue is used to capture messages from multiple request
 	// processing goroutines while writing them to the client connection
 	// from a single goroutine via sendFromQueue. We must keep track of
@@ -104,6 +272,59 @@ type DebugSession struct {
 	// the sendFromQueue goroutine that it can exit.
 	sendQueue chan dap.Message
 	sendWg    sync.WaitGroup
+	// sendLock 保护sendClosed，关闭以后send不再向sendQueue写入
+	sendLock   sync.RWMutex
+	sendClosed bool
+	// sendDone sendFromQueue写完所有消息以后关闭
+	sendDone chan struct{}
+}
+
+// launchArguments launch请求的参数
+// 可以直接指定可执行文件，也可以只提供源代码和语言，由服务端编译
+type launchArguments struct {
+	// Program 可执行文件路径
+	Program string `json:"program"`
+	// Code 用户源代码，Program为空时编译该代码
+	Code string `json:"code"`
+	// SourcePath 用户代码在客户端的路径，编译诊断信息、断点以及栈帧使用该路径，为空时使用main.c或main.cpp
+	SourcePath string `json:"sourcePath"`
+	// Language 调试语言，c或者cpp
+	Language string            `json:"language"`
+	Args     []string          `json:"args"`
+	Env      map[string]string `json:"env"`
+	Cwd      string            `json:"cwd"`
+	// Record 为true时开启执行记录，用于反向调试
+	Record bool `json:"record"`
+}
+
+// attachArguments attach请求的参数
+type attachArguments struct {
+	// ProcessId 需要附加的进程id
+	ProcessId int `json:"processId"`
+	// Program 进程对应的可执行文件，用于加载符号，可以为空
+	Program  string `json:"program"`
+	Language string `json:"language"`
+	// Record 为true时开启执行记录，用于反向调试
+	Record bool `json:"record"`
+}
+
+// SendToConsoleRequest 自定义请求，把用户的输入写入用户程序的标准输入
+type SendToConsoleRequest struct {
+	dap.Request
+	Arguments sendToConsoleArguments `json:"arguments"`
+}
+
+// sendToConsoleArguments sendToConsole请求的参数
+type sendToConsoleArguments struct {
+	// Content 原样写入的内容，不会追加换行
+	Content string `json:"content"`
+	// EOF 为true时在Content之后发送EOF(Ctrl-D)
+	EOF bool `json:"eof"`
+}
+
+// SendToConsoleResponse sendToConsole请求的响应
+type SendToConsoleResponse struct {
+	dap.Response
 }
 
 // -----------------------------------------------------------------------
@@ -118,13 +339,18 @@ func (d *DebugSession) onInitializeRequest(request *dap.InitializeRequest) {
 	response := &dap.InitializeResponse{}
 	response.Response = *newResponse(request.Seq, request.Command)
 	response.Body.SupportsConfigurationDoneRequest = true
-	response.Body.SupportsFunctionBreakpoints = false
-	response.Body.SupportsConditionalBreakpoints = false
-	response.Body.SupportsHitConditionalBreakpoints = false
-	response.Body.SupportsEvaluateForHovers = false
-	response.Body.ExceptionBreakpointFilters = []dap.ExceptionBreakpointsFilter{}
-	response.Body.SupportsStepBack = false
-	response.Body.SupportsSetVariable = false
+	response.Body.SupportsFunctionBreakpoints = true
+	response.Body.SupportsConditionalBreakpoints = true
+	response.Body.SupportsHitConditionalBreakpoints = true
+	response.Body.SupportsEvaluateForHovers = true
+	response.Body.ExceptionBreakpointFilters = []dap.ExceptionBreakpointsFilter{
+		{Filter: string(constants.SegfaultFilter), Label: "Segmentation fault", Description: "SIGSEGV and SIGBUS", Default: true},
+		{Filter: string(constants.AbortFilter), Label: "Abort", Description: "SIGABRT, raised by abort() and failed assertions", Default: true},
+		{Filter: string(constants.FPEFilter), Label: "Arithmetic exception", Description: "SIGFPE, such as division by zero", Default: true},
+		{Filter: string(constants.AllSignalsFilter), Label: "All signals", Description: "Stop on every signal received by the program"},
+	}
+	response.Body.SupportsStepBack = true
+	response.Body.SupportsSetVariable = true
 	response.Body.SupportsRestartFrame = false
 	response.Body.SupportsGotoTargetsRequest = false
 	response.Body.SupportsStepInTargetsRequest = false
@@ -136,28 +362,144 @@ func (d *DebugSession) onInitializeRequest(request *dap.InitializeRequest) {
 	response.Body.SupportsRestartRequest = false
 	response.Body.SupportsExceptionOptions = false
 	response.Body.SupportsValueFormattingOptions = false
-	response.Body.SupportsExceptionInfoRequest = false
+	response.Body.SupportsExceptionInfoRequest = true
 	response.Body.SupportTerminateDebuggee = false
 	response.Body.SupportsDelayedStackTraceLoading = false
 	response.Body.SupportsLoadedSourcesRequest = false
-	response.Body.SupportsLogPoints = false
+	response.Body.SupportsLogPoints = true
 	response.Body.SupportsTerminateThreadsRequest = false
-	response.Body.SupportsSetExpression = false
+	response.Body.SupportsSetExpression = true
 	response.Body.SupportsTerminateRequest = false
-	response.Body.SupportsDataBreakpoints = false
-	response.Body.SupportsReadMemoryRequest = false
-	response.Body.SupportsDisassembleRequest = false
+	response.Body.SupportsDataBreakpoints = true
+	response.Body.SupportsReadMemoryRequest = true
+	response.Body.SupportsWriteMemoryRequest = true
+	response.Body.SupportsDisassembleRequest = true
+	response.Body.SupportsSteppingGranularity = true
 	response.Body.SupportsCancelRequest = false
 	response.Body.SupportsBreakpointLocationsRequest = false
-	// This is a fake set up, so we can start "accepting" configuration
-	// requests for setting breakpoints, etc from the client at any time.
-	// Notify the client with an 'initialized' event. The client will end
-	// the configuration sequence with 'configurationDone' request.
-	e := &dap.InitializedEvent{Event: *newEvent("initialized")}
-	d.send(e)
 	d.send(response)
 }
 
+func (d *DebugSession) onLaunchRequest(request *dap.LaunchRequest) {
+	var args launchArguments
+	if err := json.Unmarshal(request.Arguments, &args); err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.FailedToLaunch, "Failed to launch", err.Error(), true)
+		return
+	}
+	if args.Language == "" {
+		args.Language = string(constants.LanguageC)
+	}
+	// 同一个会话重复launch时，先结束上一次调试
+	d.terminateDebugger()
+
+	execFile := args.Program
+	var sourceMap map[string]string
+	if execFile == "" {
+		if args.Code == "" {
+			d.sendErrorResponseWithOpts(request.Request, constants.FailedToLaunch, "Failed to launch", "program or code is required", true)
+			return
+		}
+		var err error
+		if execFile, err = d.compile(args.Language, args.Code, args.SourcePath); err != nil {
+			d.sendErrorResponseWithOpts(request.Request, constants.FailedToLaunch, "Failed to launch", err.Error(), true)
+			return
+		}
+		// 客户端使用sourcePath设置断点，栈帧也不返回服务端的临时目录
+		sourceMap = clientSourceMap(d.workPath, args.Language, args.SourcePath)
+	}
+	err := d.startDebugger(args.Language, &debugger.StartOption{
+		ExecFile:  execFile,
+		MainCode:  args.Code,
+		Args:      args.Args,
+		Env:       args.Env,
+		Cwd:       args.Cwd,
+		SourceMap: sourceMap,
+		Record:    args.Record,
+	})
+	if err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.FailedToLaunch, "Failed to launch", err.Error(), true)
+		return
+	}
+	response := &dap.LaunchResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	d.send(response)
+	d.sendInitializedEvent()
+}
+
+func (d *DebugSession) onAttachRequest(request *dap.AttachRequest) {
+	var args attachArguments
+	if err := json.Unmarshal(request.Arguments, &args); err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.FailedToAttach, "Failed to attach", err.Error(), true)
+		return
+	}
+	if args.ProcessId == 0 {
+		d.sendErrorResponseWithOpts(request.Request, constants.FailedToAttach, "Failed to attach", "processId is required", true)
+		return
+	}
+	if args.Language == "" {
+		args.Language = string(constants.LanguageC)
+	}
+	d.terminateDebugger()
+	err := d.startDebugger(args.Language, &debugger.StartOption{
+		ExecFile:  args.Program,
+		ProcessId: args.ProcessId,
+		Record:    args.Record,
+	})
+	if err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.FailedToAttach, "Failed to attach", err.Error(), true)
+		return
+	}
+	response := &dap.AttachResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	d.send(response)
+	d.sendInitializedEvent()
+}
+
+// compile 在服务端编译用户代码，并通过compile事件把编译结果和诊断信息发送给客户端
+func (d *DebugSession) compile(language string, code string, sourcePath string) (string, error) {
+	d.workPath = newWorkPath()
+	execFile, output, err := compileFile(d.workPath, language, code)
+	output = clientCompileOutput(output, d.workPath, language, sourcePath)
+	if err != nil {
+		message := output
+		if message == "" {
+			message = err.Error()
+		}
+		event := debugger.NewCompileEvent(false, message)
+		event.Diagnostics = debugger.ParseCompileDiagnostics(output)
+		d.send(debugger.NewCompileEventMessage(event))
+		if output == "" {
+			return "", err
+		}
+		return "", e.ErrCompileFailed
+	}
+	event := debugger.NewCompileEvent(true, debugger.CompileSuccessEvent.Message)
+	event.Diagnostics = debugger.ParseCompileDiagnostics(output)
+	d.send(debugger.NewCompileEventMessage(event))
+	return execFile, nil
+}
+
+// startDebugger 创建并启动调试器，调试事件只发送给当前会话
+func (d *DebugSession) startDebugger(language string, option *debugger.StartOption) error {
+	option.Callback = d.onDebuggerEvent
+	debug, err := createDebugger(language, option)
+	if err != nil {
+		if debug != nil {
+			_ = debug.Terminate()
+		}
+		return err
+	}
+	d.debugger = debug
+	return nil
+}
+
+// sendInitializedEvent 调试目标准备完成以后通知客户端可以开始发送断点等配置请求，
+// 客户端会以configurationDone请求结束配置过程
+func (d *DebugSession) sendInitializedEvent() {
+	event := &dap.InitializedEvent{Event: *newEvent("initialized")}
+	d.send(event)
+}
+
 func (d *DebugSession) onTerminateRequest(request *dap.TerminateRequest) {
 	err := d.debugger.Terminate()
 	if err != nil {
@@ -166,18 +508,82 @@ func (d *DebugSession) onTerminateRequest(request *dap.TerminateRequest) {
 	d.send(newErrorResponse(request.Seq, request.Command, "TerminateRequest is not yet supported"))
 }
 
+func (d *DebugSession) onDisconnectRequest(request *dap.DisconnectRequest) {
+	// 断开连接时终止调试器，gdb进程随会话一起销毁
+	d.terminateDebugger()
+	response := &dap.DisconnectResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	d.send(response)
+}
+
 func (d *DebugSession) onSetBreakpointsRequest(request *dap.SetBreakpointsRequest) {
-	err := d.debugger.SetBreakpoints(request.Arguments.Source, request.Arguments.Breakpoints)
+	breakpoints, err := d.debugger.SetBreakpoints(request.Arguments.Source, request.Arguments.Breakpoints)
 	if err != nil {
 		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
 	}
 	response := &dap.SetBreakpointsResponse{}
 	response.Response = *newResponse(request.Seq, request.Command)
-	response.Body.Breakpoints = make([]dap.Breakpoint, len(request.Arguments.Breakpoints))
-	for i, b := range request.Arguments.Breakpoints {
-		response.Body.Breakpoints[i].Line = b.Line
-		response.Body.Breakpoints[i].Verified = true
+	response.Body.Breakpoints = breakpoints
+	d.send(response)
+}
+
+func (d *DebugSession) onSetFunctionBreakpointsRequest(request *dap.SetFunctionBreakpointsRequest) {
+	breakpoints, err := d.debugger.SetFunctionBreakpoints(request.Arguments.Breakpoints)
+	if err != nil {
+		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
 	}
+	response := &dap.SetFunctionBreakpointsResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body.Breakpoints = breakpoints
+	d.send(response)
+}
+
+func (d *DebugSession) onSetExceptionBreakpointsRequest(request *dap.SetExceptionBreakpointsRequest) {
+	if err := d.debugger.SetExceptionBreakpoints(request.Arguments.Filters); err != nil {
+		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
+	}
+	response := &dap.SetExceptionBreakpointsResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	d.send(response)
+}
+
+func (d *DebugSession) onExceptionInfoRequest(request *dap.ExceptionInfoRequest) {
+	body, err := d.debugger.ExceptionInfo(request.Arguments.ThreadId)
+	if err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.UnableToGetExceptionInfo, "Unable to get exception info", err.Error(), true)
+		return
+	}
+	response := &dap.ExceptionInfoResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body = *body
+	d.send(response)
+}
+
+func (d *DebugSession) onDataBreakpointInfoRequest(request *dap.DataBreakpointInfoRequest) {
+	args := request.Arguments
+	body, err := d.debugger.DataBreakpointInfo(args.VariablesReference, args.Name, args.FrameId)
+	if err != nil {
+		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
+	}
+	response := &dap.DataBreakpointInfoResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body = *body
+	d.send(response)
+}
+
+func (d *DebugSession) onSetDataBreakpointsRequest(request *dap.SetDataBreakpointsRequest) {
+	breakpoints, err := d.debugger.SetDataBreakpoints(request.Arguments.Breakpoints)
+	if err != nil {
+		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
+	}
+	response := &dap.SetDataBreakpointsResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body.Breakpoints = breakpoints
 	d.send(response)
 }
 
@@ -192,19 +598,52 @@ func (d *DebugSession) onConfigurationDoneRequest(request *dap.ConfigurationDone
 }
 
 func (d *DebugSession) onContinueRequest(request *dap.ContinueRequest) {
-	err := d.debugger.Continue()
+	err := d.debugger.Continue(request.Arguments.ThreadId)
 	if err != nil {
 		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
 	}
 	response := &dap.ContinueResponse{}
 	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body.AllThreadsContinued = true
+	d.send(response)
+}
+
+func (d *DebugSession) onPauseRequest(request *dap.PauseRequest) {
+	if err := d.debugger.Pause(); err != nil {
+		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
+	}
+	response := &dap.PauseResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	d.send(response)
+}
+
+func (d *DebugSession) onStepBackRequest(request *dap.StepBackRequest) {
+	if err := d.debugger.StepBack(request.Arguments.ThreadId); err != nil {
+		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
+	}
+	response := &dap.StepBackResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	d.send(response)
+}
+
+func (d *DebugSession) onReverseContinueRequest(request *dap.ReverseContinueRequest) {
+	if err := d.debugger.ReverseContinue(request.Arguments.ThreadId); err != nil {
+		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
+	}
+	response := &dap.ReverseContinueResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
 	d.send(response)
 }
 
 func (d *DebugSession) onNextRequest(request *dap.NextRequest) {
-	err := d.debugger.StepOver()
+	err := d.debugger.StepOver(request.Arguments.ThreadId, request.Arguments.Granularity)
 	if err != nil {
 		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
 	}
 	response := &dap.NextResponse{}
 	response.Response = *newResponse(request.Seq, request.Command)
@@ -212,9 +651,10 @@ func (d *DebugSession) onNextRequest(request *dap.NextRequest) {
 }
 
 func (d *DebugSession) onStepInRequest(request *dap.StepInRequest) {
-	err := d.debugger.StepIn()
+	err := d.debugger.StepIn(request.Arguments.ThreadId, request.Arguments.Granularity)
 	if err != nil {
 		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
 	}
 	response := &dap.StepInResponse{}
 	response.Response = *newResponse(request.Seq, request.Command)
@@ -222,19 +662,33 @@ func (d *DebugSession) onStepInRequest(request *dap.StepInRequest) {
 }
 
 func (d *DebugSession) onStepOutRequest(request *dap.StepOutRequest) {
-	err := d.debugger.StepOut()
+	err := d.debugger.StepOut(request.Arguments.ThreadId)
 	if err != nil {
 		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
 	}
 	response := &dap.StepOutResponse{}
 	response.Response = *newResponse(request.Seq, request.Command)
 	d.send(response)
 }
 
+func (d *DebugSession) onThreadsRequest(request *dap.ThreadsRequest) {
+	threads, err := d.debugger.GetThreads()
+	if err != nil {
+		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
+	}
+	response := &dap.ThreadsResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body = dap.ThreadsResponseBody{Threads: threads}
+	d.send(response)
+}
+
 func (d *DebugSession) onStackTraceRequest(request *dap.StackTraceRequest) {
-	stacktrace, err := d.debugger.GetStackTrace()
+	stacktrace, err := d.debugger.GetStackTrace(request.Arguments.ThreadId)
 	if err != nil {
 		d.send(newErrorResponse(request.Seq, request.Command, err.Error()))
+		return
 	}
 	response := &dap.StackTraceResponse{}
 	response.Response = *newResponse(request.Seq, request.Command)
@@ -245,6 +699,51 @@ func (d *DebugSession) onStackTraceRequest(request *dap.StackTraceRequest) {
 	d.send(response)
 }
 
+func (d *DebugSession) onDisassembleRequest(request *dap.DisassembleRequest) {
+	args := request.Arguments
+	instructions, err := d.debugger.Disassemble(args.MemoryReference, args.Offset, args.InstructionOffset, args.InstructionCount)
+	if err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.UnableToDisassemble, "Unable to disassemble", err.Error(), true)
+		return
+	}
+	response := &dap.DisassembleResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body = dap.DisassembleResponseBody{Instructions: instructions}
+	d.send(response)
+}
+
+func (d *DebugSession) onReadMemoryRequest(request *dap.ReadMemoryRequest) {
+	args := request.Arguments
+	body, err := d.debugger.ReadMemory(args.MemoryReference, args.Offset, args.Count)
+	if err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.UnableToReadMemory, "Unable to read memory", err.Error(), true)
+		return
+	}
+	response := &dap.ReadMemoryResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body = *body
+	d.send(response)
+}
+
+func (d *DebugSession) onWriteMemoryRequest(request *dap.WriteMemoryRequest) {
+	args := request.Arguments
+	data, err := base64.StdEncoding.DecodeString(args.Data)
+	if err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.UnableToWriteMemory, "Unable to write memory", err.Error(), true)
+		return
+	}
+	written, err := d.debugger.WriteMemory(args.MemoryReference, args.Offset, data)
+	if err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.UnableToWriteMemory, "Unable to write memory", err.Error(), true)
+		return
+	}
+	response := &dap.WriteMemoryResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body = dap.WriteMemoryResponseBody{BytesWritten: written}
+	d.send(response)
+	d.sendInvalidatedEvent()
+}
+
 func (d *DebugSession) onScopesRequest(request *dap.ScopesRequest) {
 	scopes, err := d.debugger.GetScopes(request.Arguments.FrameId)
 	if err != nil {
@@ -271,6 +770,92 @@ func (d *DebugSession) onVariablesRequest(request *dap.VariablesRequest) {
 	d.send(response)
 }
 
+func (d *DebugSession) onEvaluateRequest(request *dap.EvaluateRequest) {
+	args := request.Arguments
+	// stdin上下文的表达式作为一行输入写入用户程序
+	if args.Context == "stdin" {
+		if err := d.debugger.SendToConsole(args.Expression+"\n", false); err != nil {
+			d.sendErrorResponseWithOpts(request.Request, constants.UnableToSendToConsole, "Unable to send to console", err.Error(), false)
+			return
+		}
+		response := &dap.EvaluateResponse{}
+		response.Response = *newResponse(request.Seq, request.Command)
+		d.send(response)
+		return
+	}
+	variable, err := d.debugger.Evaluate(args.Expression, args.FrameId, args.Context)
+	if err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.UnableToEvaluateExpression, "Unable to evaluate expression", err.Error(), false)
+		return
+	}
+	response := &dap.EvaluateResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body = dap.EvaluateResponseBody{
+		Result:             variable.Value,
+		Type:               variable.Type,
+		VariablesReference: variable.VariablesReference,
+		IndexedVariables:   variable.IndexedVariables,
+	}
+	d.send(response)
+}
+
+func (d *DebugSession) onSetVariableRequest(request *dap.SetVariableRequest) {
+	args := request.Arguments
+	variable, err := d.debugger.SetVariable(args.VariablesReference, args.Name, args.Value)
+	if err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.UnableToSetVariable, "Unable to set variable", err.Error(), true)
+		return
+	}
+	response := &dap.SetVariableResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body = dap.SetVariableResponseBody{
+		Value:              variable.Value,
+		Type:               variable.Type,
+		VariablesReference: variable.VariablesReference,
+		IndexedVariables:   variable.IndexedVariables,
+	}
+	d.send(response)
+	d.sendInvalidatedEvent()
+}
+
+func (d *DebugSession) onSetExpressionRequest(request *dap.SetExpressionRequest) {
+	args := request.Arguments
+	variable, err := d.debugger.SetExpression(args.Expression, args.Value, args.FrameId)
+	if err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.UnableToSetVariable, "Unable to set expression", err.Error(), true)
+		return
+	}
+	response := &dap.SetExpressionResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	response.Body = dap.SetExpressionResponseBody{
+		Value:              variable.Value,
+		Type:               variable.Type,
+		VariablesReference: variable.VariablesReference,
+		IndexedVariables:   variable.IndexedVariables,
+	}
+	d.send(response)
+	d.sendInvalidatedEvent()
+}
+
+func (d *DebugSession) onSendToConsoleRequest(request *SendToConsoleRequest) {
+	args := request.Arguments
+	if err := d.debugger.SendToConsole(args.Content, args.EOF); err != nil {
+		d.sendErrorResponseWithOpts(request.Request, constants.UnableToSendToConsole, "Unable to send to console", err.Error(), false)
+		return
+	}
+	response := &SendToConsoleResponse{}
+	response.Response = *newResponse(request.Seq, request.Command)
+	d.send(response)
+}
+
+// sendInvalidatedEvent 变量被修改以后，通知客户端刷新变量相关的视图
+func (d *DebugSession) sendInvalidatedEvent() {
+	d.send(&dap.InvalidatedEvent{
+		Event: *newEvent("invalidated"),
+		Body:  dap.InvalidatedEventBody{Areas: []dap.InvalidatedAreas{"variables"}},
+	})
+}
+
 // sendErrorResponseWithOpts offers configuration options.
 //
 //	showUser - if true, the error will be shown to the user (e.g. via a visible pop-up)
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"github.com/google/go-dap"
+	"github.com/stretchr/testify/assert"
+)
+
+// testMessage 测试客户端收到的消息，只解析测试需要的字段
+type testMessage struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// testClient 通过net.Pipe和DebugSession通信的测试客户端
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	seq    int
+}
+
+func newTestClient(t *testing.T) *testClient {
+	server, client := net.Pipe()
+	go serveSession(newStreamConn(server), "pipe")
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return &testClient{t: t, conn: client, reader: bufio.NewReader(client)}
+}
+
+// request 发送请求，返回请求的seq
+func (c *testClient) request(command string, arguments interface{}) int {
+	c.seq++
+	content, err := json.Marshal(map[string]interface{}{
+		"seq":       c.seq,
+		"type":      "request",
+		"command":   command,
+		"arguments": arguments,
+	})
+	assert.Nil(c.t, err)
+	assert.Nil(c.t, dap.WriteBaseMessage(c.conn, content))
+	return c.seq
+}
+
+func (c *testClient) read() testMessage {
+	content, err := dap.ReadBaseMessage(c.reader)
+	if err != nil {
+		c.t.Fatalf("read message fail, err = %s", err)
+	}
+	var message testMessage
+	assert.Nil(c.t, json.Unmarshal(content, &message))
+	return message
+}
+
+// expectResponse 读取消息直到收到请求seq的响应，body不为nil时解析响应的body
+func (c *testClient) expectResponse(seq int, body interface{}) testMessage {
+	for {
+		message := c.read()
+		if message.Type != "response" || message.RequestSeq != seq {
+			continue
+		}
+		if !message.Success {
+			c.t.Fatalf("%s fail, message = %s", message.Command, message.Message)
+		}
+		if body != nil {
+			assert.Nil(c.t, json.Unmarshal(message.Body, body))
+		}
+		return message
+	}
+}
+
+// expectEvent 读取消息直到收到名为event的事件
+func (c *testClient) expectEvent(event string) testMessage {
+	for {
+		if message := c.read(); message.Type == "event" && message.Event == event {
+			return message
+		}
+	}
+}
+
+func TestLaunchBreakpointWithSourcePath(t *testing.T) {
+	if _, err := exec.LookPath("gdb"); err != nil {
+		t.Skip("gdb is not installed")
+	}
+	const sourcePath = "/home/user/project/hello.c"
+	client := newTestClient(t)
+	client.expectResponse(client.request("initialize", map[string]interface{}{"adapterID": "go-debugger"}), nil)
+	client.expectResponse(client.request("launch", map[string]interface{}{
+		"language":   "c",
+		"sourcePath": sourcePath,
+		"code":       "#include <stdio.h>\n\nint main() {\n    int a = 1;\n    printf(\"%d\\n\", a);\n    return 0;\n}\n",
+	}), nil)
+
+	// 客户端使用自己的路径设置断点
+	var breakpoints dap.SetBreakpointsResponseBody
+	client.expectResponse(client.request("setBreakpoints", map[string]interface{}{
+		"source":      map[string]interface{}{"path": sourcePath},
+		"breakpoints": []map[string]interface{}{{"line": 5}},
+	}), &breakpoints)
+	assert.Equal(t, 1, len(breakpoints.Breakpoints))
+	assert.True(t, breakpoints.Breakpoints[0].Verified)
+	assert.Equal(t, 5, breakpoints.Breakpoints[0].Line)
+
+	client.request("configurationDone", nil)
+	client.expectEvent("stopped")
+
+	// 栈帧返回客户端的路径，不暴露服务端的临时目录
+	var stackTrace dap.StackTraceResponseBody
+	client.expectResponse(client.request("stackTrace", map[string]interface{}{"threadId": 1}), &stackTrace)
+	assert.NotEmpty(t, stackTrace.StackFrames)
+	assert.Equal(t, 5, stackTrace.StackFrames[0].Line)
+	assert.Equal(t, sourcePath, stackTrace.StackFrames[0].Source.Path)
+	assert.Equal(t, "hello.c", stackTrace.StackFrames[0].Source.Name)
+
+	client.expectResponse(client.request("disconnect", nil), nil)
+}
+
+func TestAllSignalsExceptionBreakpoint(t *testing.T) {
+	if _, err := exec.LookPath("gdb"); err != nil {
+		t.Skip("gdb is not installed")
+	}
+	client := newTestClient(t)
+	client.expectResponse(client.request("initialize", map[string]interface{}{"adapterID": "go-debugger"}), nil)
+	client.expectResponse(client.request("launch", map[string]interface{}{
+		"language": "c",
+		"code":     "#include <signal.h>\n\nvoid handler(int sig) {}\n\nint main() {\n    signal(SIGUSR1, handler);\n    raise(SIGUSR1);\n    return 0;\n}\n",
+	}), nil)
+	// 开启all过滤器以后，程序收到的任何信号都会停止，包括程序自己处理的SIGUSR1
+	client.expectResponse(client.request("setExceptionBreakpoints", map[string]interface{}{
+		"filters": []string{"all"},
+	}), nil)
+	client.request("configurationDone", nil)
+
+	var stopped dap.StoppedEventBody
+	assert.Nil(t, json.Unmarshal(client.expectEvent("stopped").Body, &stopped))
+	assert.Equal(t, "exception", stopped.Reason)
+	var exceptionInfo dap.ExceptionInfoResponseBody
+	client.expectResponse(client.request("exceptionInfo", map[string]interface{}{"threadId": stopped.ThreadId}), &exceptionInfo)
+	assert.Equal(t, "SIGUSR1", exceptionInfo.ExceptionId)
+
+	client.expectResponse(client.request("disconnect", nil), nil)
+}
+
+func TestSendSeqIncreasing(t *testing.T) {
+	server, client := net.Pipe()
+	session := newDebugSession(newStreamConn(server), "pipe")
+	go session.sendFromQueue()
+
+	// 请求协程发送响应，调试器的协程同时发送事件
+	const senders, messages = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < messages; j++ {
+				if j%2 == 0 {
+					session.send(&dap.OutputEvent{Event: *newEvent("output"), Body: dap.OutputEventBody{Output: "output"}})
+					continue
+				}
+				response := &dap.ContinueResponse{}
+				response.Response = *newResponse(j, "continue")
+				session.send(response)
+			}
+		}()
+	}
+	closed := make(chan struct{})
+	go func() {
+		wg.Wait()
+		session.close()
+		close(closed)
+	}()
+
+	reader := bufio.NewReader(client)
+	previous := 0
+	for i := 0; i < senders*messages; i++ {
+		content, err := dap.ReadBaseMessage(reader)
+		if err != nil {
+			t.Fatalf("read message fail, err = %s", err)
+		}
+		var message testMessage
+		assert.Nil(t, json.Unmarshal(content, &message))
+		// seq从1开始严格递增
+		assert.Equal(t, previous+1, message.Seq)
+		previous = message.Seq
+	}
+	<-closed
+	_, err := dap.ReadBaseMessage(reader)
+	assert.NotNil(t, err)
+}
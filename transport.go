@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"github.com/fansqz/go-debugger/utils/websocket"
+	"github.com/google/go-dap"
+	"io"
+	"log"
+	"net"
+	"net/http"
+)
+
+// messageConn 会话与客户端之间的连接，每次读写一条完整的DAP消息(JSON)
+type messageConn interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage(content []byte) error
+	Close() error
+}
+
+// streamConn 在字节流上传输DAP消息，消息之间使用Content-Length头分隔，用于TCP和标准输入输出
+type streamConn struct {
+	closer io.Closer
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+func newStreamConn(conn io.ReadWriteCloser) *streamConn {
+	return &streamConn{
+		closer: conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+	}
+}
+
+func (c *streamConn) ReadMessage() ([]byte, error) {
+	return dap.ReadBaseMessage(c.reader)
+}
+
+func (c *streamConn) WriteMessage(content []byte) error {
+	if err := dap.WriteBaseMessage(c.writer, content); err != nil {
+		return err
+	}
+	return c.writer.Flush()
+}
+
+func (c *streamConn) Close() error {
+	return c.closer.Close()
+}
+
+// stdioConn 把标准输入输出包装成一个连接
+type stdioConn struct {
+	io.Reader
+	io.Writer
+}
+
+// Close 会话结束以后进程随之退出，不需要关闭标准输入输出
+func (stdioConn) Close() error {
+	return nil
+}
+
+// webSocketPath WebSocket调试会话的路径
+const webSocketPath = "/dap"
+
+// serveWebSocket 在listener上提供服务，每个WebSocket连接是一个独立的调试会话，每条WebSocket消息是一条协议消息
+// allowedOrigins为允许连接的浏览器页面来源，为空时拒绝所有浏览器页面，非浏览器客户端总是允许连接
+func serveWebSocket(listener net.Listener, allowedOrigins []string) error {
+	checkOrigin := websocket.OriginChecker(allowedOrigins)
+	mux := http.NewServeMux()
+	mux.HandleFunc(webSocketPath, func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, checkOrigin)
+		if err != nil {
+			log.Printf("WebSocket upgrade fail, origin = %s, err = %s\n", r.Header.Get("Origin"), err)
+			return
+		}
+		sessionHandler(conn, conn.RemoteAddr().String())
+	})
+	return http.Serve(listener, mux)
+}
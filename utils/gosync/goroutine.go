@@ -2,7 +2,7 @@ package gosync
 
 import (
 	"context"
-	"fmt"
+	"log"
 )
 
 // Go 封装的go协程工具，会兜住panic，但是目前只能传递ctx
@@ -11,7 +11,7 @@ func Go(ctx context.Context, task func(ctx context.Context)) {
 		defer func() {
 			// 在每个协程内部接收该协程自身抛出来的 panic
 			if err := recover(); err != nil {
-				fmt.Println("defer", err)
+				log.Println("defer", err)
 			}
 		}()
 
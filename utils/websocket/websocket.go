@@ -0,0 +1,298 @@
+// Package websocket 基于标准库实现的WebSocket(RFC 6455)服务端，
+// 只支持调试协议需要的功能：文本和二进制消息、分片、ping/pong以及关闭握手
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// acceptGUID 计算Sec-WebSocket-Accept时拼接的固定值
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// MaxMessageSize 单条消息的最大长度，超出时关闭连接
+const MaxMessageSize = 32 << 20
+
+// 帧的操作码
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// 关闭帧的状态码
+const (
+	closeNormal          = 1000
+	closeProtocolError   = 1002
+	closeMessageTooLarge = 1009
+)
+
+var (
+	ErrBadHandshake     = errors.New("websocket: bad handshake")
+	ErrOriginNotAllowed = errors.New("websocket: origin not allowed")
+	errProtocol         = errors.New("websocket: protocol error")
+	errMessageTooLarge  = errors.New("websocket: message too large")
+)
+
+// Conn WebSocket连接，ReadMessage只能在一个协程中调用，WriteMessage可以并发调用
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	writeLock sync.Mutex
+	closed    bool
+}
+
+// Upgrade 校验握手请求并把http连接升级为WebSocket连接
+// checkOrigin不为nil时用来校验请求的Origin，返回false时拒绝连接
+func Upgrade(w http.ResponseWriter, r *http.Request, checkOrigin func(r *http.Request) bool) (*Conn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported websocket version", http.StatusUpgradeRequired)
+		return nil, ErrBadHandshake
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		http.Error(w, "invalid Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	if checkOrigin != nil && !checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, ErrOriginNotAllowed
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, ErrBadHandshake
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err = conn.Write([]byte(response)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, reader: rw.Reader}, nil
+}
+
+// acceptKey 根据客户端的Sec-WebSocket-Key计算Sec-WebSocket-Accept
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains 判断请求头中逗号分隔的值是否包含token，忽略大小写
+func headerContains(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RemoteAddr 客户端的地址
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// ReadMessage 读取一条完整的文本或者二进制消息，分片的消息会被合并
+// 期间收到的ping会自动回复pong，收到关闭帧时回复关闭帧并返回io.EOF
+func (c *Conn) ReadMessage() ([]byte, error) {
+	// 已经发送了关闭帧，不再读取消息
+	if c.isClosed() {
+		return nil, io.EOF
+	}
+	var message []byte
+	started := false
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.closeWithCode(closeProtocolError)
+			} else if errors.Is(err, errMessageTooLarge) {
+				c.closeWithCode(closeMessageTooLarge)
+			}
+			return nil, err
+		}
+		switch opcode {
+		case opPing:
+			if err = c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.closeWithCode(closeNormal)
+			return nil, io.EOF
+		case opText, opBinary:
+			if started {
+				c.closeWithCode(closeProtocolError)
+				return nil, errProtocol
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				c.closeWithCode(closeProtocolError)
+				return nil, errProtocol
+			}
+		default:
+			c.closeWithCode(closeProtocolError)
+			return nil, errProtocol
+		}
+		if len(message)+len(payload) > MaxMessageSize {
+			c.closeWithCode(closeMessageTooLarge)
+			return nil, errMessageTooLarge
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// readFrame 读取一个帧，客户端发送的帧必须带掩码
+func (c *Conn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	// 没有协商扩展，保留位必须为0
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, errProtocol
+	}
+	opcode := header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	if !masked {
+		return false, 0, nil, errProtocol
+	}
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	// 控制帧不能分片，长度不能超过125
+	if opcode >= opClose && (!fin || length > 125) {
+		return false, 0, nil, errProtocol
+	}
+	if length > MaxMessageSize {
+		return false, 0, nil, errMessageTooLarge
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage 以一个文本帧发送消息
+func (c *Conn) WriteMessage(message []byte) error {
+	return c.writeFrame(opText, message)
+}
+
+// writeFrame 发送一个不分片的帧，服务端发送的帧不带掩码
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	header := []byte{0x80 | opcode}
+	switch length := len(payload); {
+	case length <= 125:
+		header = append(header, byte(length))
+	case length <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	if opcode == opClose {
+		c.closed = true
+	}
+	return nil
+}
+
+func (c *Conn) isClosed() bool {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+	return c.closed
+}
+
+// closeWithCode 发送关闭帧，之后不能再发送消息
+func (c *Conn) closeWithCode(code uint16) {
+	_ = c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, code))
+}
+
+// Close 发送关闭帧并关闭底层连接
+func (c *Conn) Close() error {
+	c.closeWithCode(closeNormal)
+	return c.conn.Close()
+}
+
+// OriginChecker 返回校验Origin的函数
+// 没有Origin的请求(非浏览器客户端)总是允许，浏览器的请求只允许allowed中的Origin，
+// "*"表示允许所有Origin，allowed为空时拒绝所有浏览器的请求
+// 不根据Host判断同源，Host可以被DNS重绑定的页面控制
+func OriginChecker(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, item := range allowed {
+			if item == "*" || strings.EqualFold(item, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
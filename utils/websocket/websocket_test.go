@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newEchoServer 启动一个把收到的消息原样发回的服务
+func newEchoServer(allowed []string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, OriginChecker(allowed))
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = conn.WriteMessage(message)
+		}
+	}))
+}
+
+// dial 完成握手，返回连接以及响应的状态行
+func dial(t *testing.T, server *httptest.Server, origin string) (net.Conn, *bufio.Reader, string) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	assert.Nil(t, err)
+	request := "GET / HTTP/1.1\r\nHost: " + strings.TrimPrefix(server.URL, "http://") + "\r\n" +
+		"Upgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n"
+	if origin != "" {
+		request += "Origin: " + origin + "\r\n"
+	}
+	_, err = conn.Write([]byte(request + "\r\n"))
+	assert.Nil(t, err)
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	assert.Nil(t, err)
+	if response.StatusCode == http.StatusSwitchingProtocols {
+		assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", response.Header.Get("Sec-WebSocket-Accept"))
+	}
+	return conn, reader, response.Status
+}
+
+// writeClientFrame 发送一个带掩码的帧
+func writeClientFrame(t *testing.T, conn net.Conn, fin bool, opcode byte, payload []byte) {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	if len(payload) <= 125 {
+		frame = append(frame, 0x80|byte(len(payload)))
+	} else {
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	_, err := conn.Write(frame)
+	assert.Nil(t, err)
+}
+
+// readServerFrame 读取一个服务端发送的帧
+func readServerFrame(t *testing.T, reader *bufio.Reader) (byte, []byte) {
+	var header [2]byte
+	_, err := io.ReadFull(reader, header[:])
+	assert.Nil(t, err)
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		_, err = io.ReadFull(reader, ext[:])
+		assert.Nil(t, err)
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	_, err = io.ReadFull(reader, payload)
+	assert.Nil(t, err)
+	return header[0] & 0x0F, payload
+}
+
+func TestEcho(t *testing.T) {
+	server := newEchoServer(nil)
+	defer server.Close()
+	conn, reader, status := dial(t, server, "")
+	defer conn.Close()
+	assert.Equal(t, "101 Switching Protocols", status)
+
+	message := `{"seq":1,"type":"request","command":"initialize"}`
+	writeClientFrame(t, conn, true, opText, []byte(message))
+	opcode, payload := readServerFrame(t, reader)
+	assert.Equal(t, byte(opText), opcode)
+	assert.Equal(t, message, string(payload))
+
+	// 分片的消息中间插入ping
+	long := strings.Repeat("x", 300)
+	writeClientFrame(t, conn, false, opText, []byte(long[:100]))
+	writeClientFrame(t, conn, true, opPing, []byte("ping"))
+	writeClientFrame(t, conn, true, opContinuation, []byte(long[100:]))
+	opcode, payload = readServerFrame(t, reader)
+	assert.Equal(t, byte(opPong), opcode)
+	assert.Equal(t, "ping", string(payload))
+	opcode, payload = readServerFrame(t, reader)
+	assert.Equal(t, byte(opText), opcode)
+	assert.Equal(t, long, string(payload))
+
+	// 关闭握手
+	writeClientFrame(t, conn, true, opClose, binary.BigEndian.AppendUint16(nil, closeNormal))
+	opcode, payload = readServerFrame(t, reader)
+	assert.Equal(t, byte(opClose), opcode)
+	assert.Equal(t, uint16(closeNormal), binary.BigEndian.Uint16(payload))
+}
+
+func TestOrigin(t *testing.T) {
+	server := newEchoServer([]string{"https://ide.example.com"})
+	defer server.Close()
+
+	conn, _, status := dial(t, server, "https://ide.example.com")
+	conn.Close()
+	assert.Equal(t, "101 Switching Protocols", status)
+
+	conn, _, status = dial(t, server, "https://evil.example.com")
+	conn.Close()
+	assert.Equal(t, "403 Forbidden", status)
+
+	// 和Host相同的Origin也需要在列表中
+	conn, _, status = dial(t, server, server.URL)
+	conn.Close()
+	assert.Equal(t, "403 Forbidden", status)
+
+	// 没有配置时拒绝所有浏览器页面，非浏览器客户端仍然可以连接
+	empty := newEchoServer(nil)
+	defer empty.Close()
+	conn, _, status = dial(t, empty, "https://ide.example.com")
+	conn.Close()
+	assert.Equal(t, "403 Forbidden", status)
+	conn, _, status = dial(t, empty, "")
+	conn.Close()
+	assert.Equal(t, "101 Switching Protocols", status)
+}
+
+func TestUnmaskedFrame(t *testing.T) {
+	server := newEchoServer(nil)
+	defer server.Close()
+	conn, reader, _ := dial(t, server, "")
+	defer conn.Close()
+
+	// 客户端的帧没有掩码，服务端以1002关闭连接
+	_, err := conn.Write([]byte{0x80 | opText, 2, 'h', 'i'})
+	assert.Nil(t, err)
+	opcode, payload := readServerFrame(t, reader)
+	assert.Equal(t, byte(opClose), opcode)
+	assert.Equal(t, uint16(closeProtocolError), binary.BigEndian.Uint16(payload))
+}